@@ -0,0 +1,297 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+const exportPageSize = 100
+
+type exportFile struct {
+	name    string
+	content interface{}
+}
+
+func (app *application) exportCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	roles, err := app.models.Roles.GetNamesForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sessions, err := app.models.Tokens.GetAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	refreshTokens, err := app.models.Tokens.GetAllForUser(data.ScopeRefresh, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	apikeys, err := app.models.APIKeys.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	movements, err := app.models.StockMovements.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	shoppinglistItems, err := app.models.ShoppingListItems.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	files := []exportFile{
+		{"user.json", user},
+		{"permissions.json", envelope{"permissions": permissions, "roles": roles}},
+		{"tokens.json", envelope{"authentication": sessions, "refresh": refreshTokens}},
+		{"apikeys.json", apikeys},
+		{"stock_movements.json", movements},
+		{"shoppinglist_items.json", shoppinglistItems},
+	}
+
+	if user.HouseholdID != 0 {
+		household, err := app.models.Households.Get(user.HouseholdID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		files = append(files, exportFile{"household.json", household})
+
+		if permissions.Include("recipies:read") {
+			recipies, err := app.exportRecipies(user.HouseholdID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			files = append(files, exportFile{"recipies.json", recipies})
+		}
+
+		if permissions.Include("availableitems:read") {
+			availableitems, err := app.exportAvailableItems(user.HouseholdID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			files = append(files, exportFile{"availableitems.json", availableitems})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="homecatalogue-export-`+strconv.FormatInt(user.ID, 10)+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "\t")
+
+		err = enc.Encode(file.content)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) exportRecipies(householdID int64) ([]*data.Recipe, error) {
+	filters := data.Filters{Page: 1, PageSize: exportPageSize, Sort: "id", SortSafelist: []string{"id"}}
+
+	recipies := []*data.Recipe{}
+
+	for {
+		page, _, err := app.models.Recipies.GetAll(householdID, "", "", []string{}, 0, 0, []string{}, filters)
+		if err != nil {
+			return nil, err
+		}
+
+		recipies = append(recipies, page...)
+
+		if len(page) < filters.PageSize {
+			return recipies, nil
+		}
+
+		filters.Page++
+	}
+}
+
+func (app *application) exportAvailableItems(householdID int64) ([]*data.AvailableItem, error) {
+	filters := data.Filters{Page: 1, PageSize: exportPageSize, Sort: "id", SortSafelist: []string{"id"}}
+
+	availableitems := []*data.AvailableItem{}
+
+	for {
+		page, _, err := app.models.AvailableItems.GetAll(householdID, 0, 0, time.Time{}, time.Time{}, nil, 0, filters)
+		if err != nil {
+			return nil, err
+		}
+
+		availableitems = append(availableitems, page...)
+
+		if len(page) < filters.PageSize {
+			return availableitems, nil
+		}
+
+		filters.Page++
+	}
+}
+
+func (app *application) deleteCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidatePasswordPlaintext(v, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user.IsLocked() {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	if !match {
+		app.failedLoginResponse(w, r, user)
+		return
+	}
+
+	soleOwner, err := app.models.Households.IsSoleOwner(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if soleOwner {
+		v.AddError("household", "you are the only owner of your household, invite another member as owner first")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	deletionAt := time.Now().Add(app.config.accounts.deletionGracePeriod)
+
+	err = app.models.Users.ScheduleDeletion(user.ID, deletionAt)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeRefresh, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.APIKeys.DeleteAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"message":               "your account is scheduled for deletion, log in and restore it before then to keep it",
+		"deletion_scheduled_at": deletionAt,
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.DeletionScheduledAt == nil {
+		v := validator.New()
+		v.AddError("user", "is not scheduled for deletion")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Users.CancelDeletion(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user.DeletionScheduledAt = nil
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) purgeDeletedAccounts() {
+	deleted, err := app.models.Users.PurgeScheduledDeletions()
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+
+	if deleted > 0 {
+		app.logger.PrintInfo("purged deleted accounts", map[string]string{
+			"count": strconv.FormatInt(deleted, 10),
+		})
+	}
+}
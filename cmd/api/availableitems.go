@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"time"
 
@@ -11,10 +12,13 @@ import (
 )
 
 func (app *application) createAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		KnownItemsID  int64     `json:"knownitems_id"`
 		ExpirationAt  time.Time `json:"expiration_at"`
 		ContainerSize int32     `json:"container_size"`
+		LocationID    *int64    `json:"location_id"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -24,9 +28,12 @@ func (app *application) createAvailableItemHandler(w http.ResponseWriter, r *htt
 	}
 
 	availableitem := &data.AvailableItem{
+		HouseholdID:   user.HouseholdID,
 		KnownItemsID:  input.KnownItemsID,
 		ExpirationAt:  input.ExpirationAt,
 		ContainerSize: input.ContainerSize,
+		Remaining:     input.ContainerSize,
+		LocationID:    input.LocationID,
 	}
 
 	v := validator.New()
@@ -36,7 +43,35 @@ func (app *application) createAvailableItemHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = app.models.AvailableItems.Insert(availableitem)
+	err = app.checkAvailableItemReferences(v, availableitem, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Without an expiration the known item's shelf life decides, and items
+	// without one expire right away like they always have.
+	if availableitem.ExpirationAt.IsZero() {
+		knownitem, err := app.models.KnownItems.Get(availableitem.KnownItemsID, user.HouseholdID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		now := time.Now()
+
+		availableitem.ExpirationAt = knownitem.ExpirationSealed(now)
+		if availableitem.ExpirationAt.IsZero() {
+			availableitem.ExpirationAt = now
+		}
+	}
+
+	err = app.models.AvailableItems.Insert(availableitem, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -52,13 +87,15 @@ func (app *application) createAvailableItemHandler(w http.ResponseWriter, r *htt
 }
 
 func (app *application) showAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	availableitem, err := app.models.AvailableItems.Get(id)
+	availableitem, err := app.models.AvailableItems.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -76,13 +113,15 @@ func (app *application) showAvailableItemHandler(w http.ResponseWriter, r *http.
 }
 
 func (app *application) updateAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	availableitem, err := app.models.AvailableItems.Get(id)
+	availableitem, err := app.models.AvailableItems.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -97,6 +136,7 @@ func (app *application) updateAvailableItemHandler(w http.ResponseWriter, r *htt
 		KnownItemsID  *int64     `json:"knownitems_id"`
 		ExpirationAt  *time.Time `json:"expiration_at"`
 		ContainerSize *int32     `json:"container_size"`
+		Remaining     *int32     `json:"remaining"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -115,6 +155,14 @@ func (app *application) updateAvailableItemHandler(w http.ResponseWriter, r *htt
 
 	if input.ContainerSize != nil {
 		availableitem.ContainerSize = *input.ContainerSize
+
+		if availableitem.Remaining > availableitem.ContainerSize {
+			availableitem.Remaining = availableitem.ContainerSize
+		}
+	}
+
+	if input.Remaining != nil {
+		availableitem.Remaining = *input.Remaining
 	}
 
 	v := validator.New()
@@ -124,7 +172,18 @@ func (app *application) updateAvailableItemHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = app.models.AvailableItems.Update(availableitem)
+	err = app.checkAvailableItemReferences(v, availableitem, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.AvailableItems.Update(availableitem, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -142,13 +201,15 @@ func (app *application) updateAvailableItemHandler(w http.ResponseWriter, r *htt
 }
 
 func (app *application) deleteAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.AvailableItems.Delete(id)
+	err = app.models.AvailableItems.Delete(id, user.HouseholdID, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -166,9 +227,15 @@ func (app *application) deleteAvailableItemHandler(w http.ResponseWriter, r *htt
 }
 
 func (app *application) listAvailableItemsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		KnownItemsID  int
+		LocationID    int
 		ExpirationAt  time.Time
+		ExpiresBefore time.Time
+		ExpiresWithin time.Duration
+		Expired       *bool
 		ContainerSize int
 		data.Filters
 	}
@@ -178,24 +245,40 @@ func (app *application) listAvailableItemsHandler(w http.ResponseWriter, r *http
 	qs := r.URL.Query()
 
 	input.KnownItemsID = app.readInt(qs, "knownitems_id", 0, v)
+	input.LocationID = app.readInt(qs, "location", 0, v)
 
 	input.ExpirationAt = app.readTime(qs, "expiration_at", time.Time{}, v)
+	input.ExpiresBefore = app.readTime(qs, "expires_before", time.Time{}, v)
+	input.ExpiresWithin = app.readDuration(qs, "expires_within", 0, v)
+	input.Expired = app.readBool(qs, "expired", nil, v)
 
 	input.ContainerSize = app.readInt(qs, "container_size", 0, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
-	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Sort = app.readString(qs, "sort", "expiration_at")
 
 	input.Filters.SortSafelist = []string{"id", "knownitems_id", "expiration_at", "container_size", "-id", "-knownitems_id", "-expiration_at", "-container_size"}
 
+	v.Check(input.ExpiresWithin >= 0, "expires_within", "must not be negative")
+
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	availableitems, metadata, err := app.models.AvailableItems.GetAll(input.KnownItemsID, input.ExpirationAt, input.ContainerSize, input.Filters)
+	// expires_within is a shorthand for expires_before relative to now, the
+	// earlier of the two wins when both are given.
+	if input.ExpiresWithin > 0 {
+		within := time.Now().Add(input.ExpiresWithin)
+
+		if input.ExpiresBefore.IsZero() || within.Before(input.ExpiresBefore) {
+			input.ExpiresBefore = within
+		}
+	}
+
+	availableitems, metadata, err := app.models.AvailableItems.GetAll(user.HouseholdID, input.KnownItemsID, input.LocationID, input.ExpirationAt, input.ExpiresBefore, input.Expired, input.ContainerSize, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -207,3 +290,300 @@ func (app *application) listAvailableItemsHandler(w http.ResponseWriter, r *http
 	}
 
 }
+
+func (app *application) consumeAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	availableitem, err := app.models.AvailableItems.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Amount      float64 `json:"amount"`
+		Measurement *int64  `json:"measurement"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	knownitem, err := app.models.KnownItems.Get(availableitem.KnownItemsID, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Measurement != nil {
+		v.Check(*input.Measurement == knownitem.Measurement, "measurement", "must be the known item's measurement")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	amount := math.Round(input.Amount)
+
+	if data.ValidateConsumptionAmount(v, amount, availableitem.Remaining); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movement, err := app.models.AvailableItems.Consume(availableitem, int32(amount), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"stock_movement": movement}
+
+	if availableitem.Remaining > 0 {
+		env["availableitem"] = availableitem
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) openAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	availableitem, err := app.models.AvailableItems.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	knownitem, err := app.models.KnownItems.Get(availableitem.KnownItemsID, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(availableitem.OpenedAt == nil, "availableitem", "has already been opened")
+	v.Check(knownitem.OpenedShelfLifeDays > 0, "opened_shelf_life_days", "must be set on the known item")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	now := time.Now()
+	expiration := knownitem.ExpirationOpened(now)
+
+	// Opening never makes an item last longer, unless it comes out of the
+	// freezer to be opened.
+	if availableitem.FrozenAt != nil || expiration.Before(availableitem.ExpirationAt) {
+		availableitem.ExpirationAt = expiration
+	}
+
+	availableitem.OpenedAt = &now
+	availableitem.FrozenAt = nil
+
+	err = app.models.AvailableItems.UpdateShelfLife(availableitem)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"availableitem": availableitem}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) freezeAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	availableitem, err := app.models.AvailableItems.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	knownitem, err := app.models.KnownItems.Get(availableitem.KnownItemsID, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(availableitem.FrozenAt == nil, "availableitem", "has already been frozen")
+	v.Check(knownitem.FrozenShelfLifeDays > 0, "frozen_shelf_life_days", "must be set on the known item")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	now := time.Now()
+
+	availableitem.ExpirationAt = knownitem.ExpirationFrozen(now)
+	availableitem.FrozenAt = &now
+
+	err = app.models.AvailableItems.UpdateShelfLife(availableitem)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"availableitem": availableitem}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) moveAvailableItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	availableitem, err := app.models.AvailableItems.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		LocationID *int64 `json:"location_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.LocationID != nil {
+		_, err = app.models.Locations.Get(*input.LocationID, user.HouseholdID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("location_id", "must reference an existing location")
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	sameLocation := input.LocationID == nil && availableitem.LocationID == nil ||
+		input.LocationID != nil && availableitem.LocationID != nil && *input.LocationID == *availableitem.LocationID
+
+	v.Check(!sameLocation, "location_id", "must differ from the item's current location")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movement, err := app.models.AvailableItems.Move(availableitem, input.LocationID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"availableitem": availableitem, "stock_movement": movement}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) checkAvailableItemReferences(v *validator.Validator, availableitem *data.AvailableItem, householdID int64) error {
+	_, err := app.models.KnownItems.Get(availableitem.KnownItemsID, householdID)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		v.AddError("knownitems_id", "must reference an existing known item")
+	}
+
+	if availableitem.LocationID != nil {
+		_, err = app.models.Locations.Get(*availableitem.LocationID, householdID)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				return err
+			}
+
+			v.AddError("location_id", "must reference an existing location")
+		}
+	}
+
+	return nil
+}
@@ -9,7 +9,11 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey          = contextKey("user")
+	apiKeyContextKey        = contextKey("apikey")
+	apiKeyCheckedContextKey = contextKey("apikeychecked")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -24,3 +28,29 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+func (app *application) contextSetAPIKey(r *http.Request, apikey *data.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, apikey)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetAPIKey(r *http.Request) *data.APIKey {
+	apikey, ok := r.Context().Value(apiKeyContextKey).(*data.APIKey)
+	if !ok {
+		return nil
+	}
+
+	return apikey
+}
+
+// contextSetAPIKeyChecked marks a request whose api key scope is checked by
+// requirePermission, so the rest of the middleware chain may let the key through.
+func (app *application) contextSetAPIKeyChecked(r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyCheckedContextKey, true)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetAPIKeyChecked(r *http.Request) bool {
+	checked, _ := r.Context().Value(apiKeyCheckedContextKey).(bool)
+	return checked
+}
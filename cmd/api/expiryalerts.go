@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+func (app *application) showExpiryAlertSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	settings, err := app.models.ExpiryAlerts.GetForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"expiry_alerts": settings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateExpiryAlertSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	settings, err := app.models.ExpiryAlerts.GetForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Enabled      *bool  `json:"enabled"`
+		LeadDays     *int32 `json:"lead_days"`
+		DeliveryHour *int32 `json:"delivery_hour"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Enabled != nil {
+		settings.Enabled = *input.Enabled
+	}
+
+	if input.LeadDays != nil {
+		settings.LeadDays = *input.LeadDays
+	}
+
+	if input.DeliveryHour != nil {
+		settings.DeliveryHour = *input.DeliveryHour
+	}
+
+	v := validator.New()
+
+	if data.ValidateExpiryAlertSettings(v, settings); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ExpiryAlerts.Update(settings)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"expiry_alerts": settings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sendExpiryAlerts mails a digest of soon to expire items to every user whose
+// delivery hour has come up since their last digest. Users without anything
+// expiring are skipped but still marked as sent, so they are not checked again
+// until the next day.
+func (app *application) sendExpiryAlerts() {
+	now := time.Now()
+
+	recipients, err := app.models.ExpiryAlerts.GetDue(now)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+
+	for _, recipient := range recipients {
+		select {
+		case <-app.shutdown:
+			return
+		default:
+		}
+
+		permissions, err := app.models.Permissions.GetAllForUser(recipient.UserID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			continue
+		}
+
+		if permissions.Include("availableitems:read") {
+			until := now.Add(time.Duration(recipient.LeadDays) * 24 * time.Hour)
+
+			items, err := app.models.ExpiryAlerts.GetExpiring(recipient.HouseholdID, now, until)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				continue
+			}
+
+			if len(items) > 0 {
+				data := map[string]interface{}{
+					"name":     recipient.Name,
+					"leadDays": recipient.LeadDays,
+					"items":    items,
+				}
+
+				err = app.mailer.Send(recipient.Email, "expiry_digest.tmpl", data)
+				if err != nil {
+					app.logger.PrintError(err, map[string]string{
+						"user_id": strconv.FormatInt(recipient.UserID, 10),
+					})
+					continue
+				}
+			}
+		}
+
+		err = app.models.ExpiryAlerts.MarkSent(recipient.UserID, now)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -45,6 +46,47 @@ func (app *application) readRecipeIngredientIDsParam(r *http.Request) (int64, in
 	return recipeid, ingredientid, nil
 }
 
+func (app *application) readShoppingListItemIDsParam(r *http.Request) (int64, int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	shoppinglistid, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	if err != nil || shoppinglistid < 1 {
+		return 0, 0, errors.New("invalid shopping list id parameter")
+	}
+
+	itemid, err := strconv.ParseInt(params.ByName("item_id"), 10, 64)
+	if err != nil || itemid < 1 {
+		return 0, 0, errors.New("invalid item id parameter")
+	}
+
+	return shoppinglistid, itemid, nil
+}
+
+func (app *application) readIngredientKnownItemIDsParam(r *http.Request) (int64, int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	ingredientid, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	if err != nil || ingredientid < 1 {
+		return 0, 0, errors.New("invalid ingredient id parameter")
+	}
+
+	knownitemsid, err := strconv.ParseInt(params.ByName("knownitems_id"), 10, 64)
+	if err != nil || knownitemsid < 1 {
+		return 0, 0, errors.New("invalid known item id parameter")
+	}
+
+	return ingredientid, knownitemsid, nil
+}
+
+func (app *application) readBearerToken(r *http.Request) (string, error) {
+	headerParts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return "", errors.New("invalid authorization header")
+	}
+
+	return headerParts[1], nil
+}
+
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 	js, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
@@ -64,6 +106,10 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+// errEmptyBody is returned by readJSON when the request has no body, so that
+// handlers whose input is optional can treat it as {}.
+var errEmptyBody = errors.New("body must not be empty")
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -91,7 +137,7 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
 
 		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
+			return errEmptyBody
 
 		case strings.HasPrefix(err.Error(), "json: unknown field "):
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
@@ -152,6 +198,22 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+func (app *application) readFloat(qs url.Values, key string, defaultValue float64, v *validator.Validator) float64 {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		v.AddError(key, "must be a number")
+		return defaultValue
+	}
+
+	return f
+}
+
 func (app *application) readTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
 	s := qs.Get(key)
 
@@ -169,6 +231,50 @@ func (app *application) readTime(qs url.Values, key string, defaultValue time.Ti
 	return t
 }
 
+func (app *application) readBool(qs url.Values, key string, defaultValue *bool, v *validator.Validator) *bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be true or false")
+		return defaultValue
+	}
+
+	return &b
+}
+
+// readDuration accepts Go durations such as "36h" as well as a whole number of
+// days such as "3d".
+func (app *application) readDuration(qs url.Values, key string, defaultValue time.Duration, v *validator.Validator) time.Duration {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	if days, found := strings.CutSuffix(s, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			v.AddError(key, "must be a duration such as 3d or 12h")
+			return defaultValue
+		}
+
+		return time.Duration(n) * 24 * time.Hour
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		v.AddError(key, "must be a duration such as 3d or 12h")
+		return defaultValue
+	}
+
+	return d
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 
@@ -184,3 +290,30 @@ func (app *application) background(fn func()) {
 		fn()
 	}()
 }
+
+// schedule runs fn in the background straight away and then once every interval,
+// until the server starts shutting down.
+func (app *application) schedule(interval time.Duration, fn func()) {
+	app.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			func() {
+				defer func() {
+					if err := recover(); err != nil {
+						app.logger.PrintError(fmt.Errorf("%s", err), nil)
+					}
+				}()
+
+				fn()
+			}()
+
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
@@ -10,9 +10,12 @@ import (
 )
 
 func (app *application) createIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
-		Name string   `json:"name"`
-		Tags []string `json:"tags"`
+		Name    string   `json:"name"`
+		Tags    []string `json:"tags"`
+		Density float64  `json:"density"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -22,8 +25,10 @@ func (app *application) createIngredientHandler(w http.ResponseWriter, r *http.R
 	}
 
 	ingredient := &data.Ingredient{
-		Name: input.Name,
-		Tags: input.Tags,
+		HouseholdID: user.HouseholdID,
+		Name:        input.Name,
+		Tags:        input.Tags,
+		Density:     input.Density,
 	}
 
 	v := validator.New()
@@ -49,13 +54,15 @@ func (app *application) createIngredientHandler(w http.ResponseWriter, r *http.R
 }
 
 func (app *application) showIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	ingredient, err := app.models.Ingredients.Get(id)
+	ingredient, err := app.models.Ingredients.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -73,13 +80,15 @@ func (app *application) showIngredientHandler(w http.ResponseWriter, r *http.Req
 }
 
 func (app *application) updateIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	ingredient, err := app.models.Ingredients.Get(id)
+	ingredient, err := app.models.Ingredients.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -91,8 +100,9 @@ func (app *application) updateIngredientHandler(w http.ResponseWriter, r *http.R
 	}
 
 	var input struct {
-		Name *string  `json:"name"`
-		Tags []string `json:"tags"`
+		Name    *string  `json:"name"`
+		Tags    []string `json:"tags"`
+		Density *float64 `json:"density"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -109,6 +119,10 @@ func (app *application) updateIngredientHandler(w http.ResponseWriter, r *http.R
 		ingredient.Tags = input.Tags
 	}
 
+	if input.Density != nil {
+		ingredient.Density = *input.Density
+	}
+
 	v := validator.New()
 
 	if data.ValidateIngredient(v, ingredient); !v.Valid() {
@@ -134,13 +148,15 @@ func (app *application) updateIngredientHandler(w http.ResponseWriter, r *http.R
 }
 
 func (app *application) deleteIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.Ingredients.Delete(id)
+	err = app.models.Ingredients.Delete(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -158,6 +174,8 @@ func (app *application) deleteIngredientHandler(w http.ResponseWriter, r *http.R
 }
 
 func (app *application) listIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		Name string
 		Tags []string
@@ -183,7 +201,7 @@ func (app *application) listIngredientsHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	ingredients, metadata, err := app.models.Ingredients.GetAll(input.Name, input.Tags, input.Filters)
+	ingredients, metadata, err := app.models.Ingredients.GetAll(user.HouseholdID, input.Name, input.Tags, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
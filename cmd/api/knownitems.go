@@ -10,6 +10,8 @@ import (
 )
 
 func (app *application) createKnownItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		SerialNumber  int64    `json:"serial_number"`
 		LongName      string   `json:"long_name"`
@@ -18,6 +20,13 @@ func (app *application) createKnownItemHandler(w http.ResponseWriter, r *http.Re
 		ItemType      int64    `json:"item_type"`
 		Measurement   int64    `json:"measurement"`
 		ContainerSize int32    `json:"container_size"`
+
+		ShelfLifeDays       int32 `json:"shelf_life_days"`
+		OpenedShelfLifeDays int32 `json:"opened_shelf_life_days"`
+		FrozenShelfLifeDays int32 `json:"frozen_shelf_life_days"`
+
+		ParLevel       float64 `json:"par_level"`
+		ParMeasurement *int64  `json:"par_measurement"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -27,6 +36,7 @@ func (app *application) createKnownItemHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	knownitem := &data.KnownItem{
+		HouseholdID:   user.HouseholdID,
 		SerialNumber:  input.SerialNumber,
 		LongName:      input.LongName,
 		ShortName:     input.ShortName,
@@ -34,6 +44,13 @@ func (app *application) createKnownItemHandler(w http.ResponseWriter, r *http.Re
 		ItemType:      input.ItemType,
 		Measurement:   input.Measurement,
 		ContainerSize: input.ContainerSize,
+
+		ShelfLifeDays:       input.ShelfLifeDays,
+		OpenedShelfLifeDays: input.OpenedShelfLifeDays,
+		FrozenShelfLifeDays: input.FrozenShelfLifeDays,
+
+		ParLevel:       input.ParLevel,
+		ParMeasurement: input.ParMeasurement,
 	}
 
 	v := validator.New()
@@ -43,6 +60,17 @@ func (app *application) createKnownItemHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	err = app.checkParMeasurement(v, knownitem)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.models.KnownItems.Insert(knownitem)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -59,13 +87,15 @@ func (app *application) createKnownItemHandler(w http.ResponseWriter, r *http.Re
 }
 
 func (app *application) showKnownItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	knownitem, err := app.models.KnownItems.Get(id)
+	knownitem, err := app.models.KnownItems.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -83,13 +113,15 @@ func (app *application) showKnownItemHandler(w http.ResponseWriter, r *http.Requ
 }
 
 func (app *application) updateKnownItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	knownitem, err := app.models.KnownItems.Get(id)
+	knownitem, err := app.models.KnownItems.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -108,6 +140,13 @@ func (app *application) updateKnownItemHandler(w http.ResponseWriter, r *http.Re
 		ItemType      *int64   `json:"item_type"`
 		Measurement   *int64   `json:"measurement"`
 		ContainerSize *int32   `json:"container_size"`
+
+		ShelfLifeDays       *int32 `json:"shelf_life_days"`
+		OpenedShelfLifeDays *int32 `json:"opened_shelf_life_days"`
+		FrozenShelfLifeDays *int32 `json:"frozen_shelf_life_days"`
+
+		ParLevel       *float64 `json:"par_level"`
+		ParMeasurement *int64   `json:"par_measurement"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -144,6 +183,31 @@ func (app *application) updateKnownItemHandler(w http.ResponseWriter, r *http.Re
 		knownitem.ContainerSize = *input.ContainerSize
 	}
 
+	if input.ShelfLifeDays != nil {
+		knownitem.ShelfLifeDays = *input.ShelfLifeDays
+	}
+
+	if input.OpenedShelfLifeDays != nil {
+		knownitem.OpenedShelfLifeDays = *input.OpenedShelfLifeDays
+	}
+
+	if input.FrozenShelfLifeDays != nil {
+		knownitem.FrozenShelfLifeDays = *input.FrozenShelfLifeDays
+	}
+
+	if input.ParLevel != nil {
+		knownitem.ParLevel = *input.ParLevel
+	}
+
+	// A par_measurement of 0 goes back to the item's own measurement.
+	if input.ParMeasurement != nil {
+		knownitem.ParMeasurement = input.ParMeasurement
+
+		if *input.ParMeasurement == 0 {
+			knownitem.ParMeasurement = nil
+		}
+	}
+
 	v := validator.New()
 
 	if data.ValidateKnownItem(v, knownitem); !v.Valid() {
@@ -151,6 +215,17 @@ func (app *application) updateKnownItemHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	err = app.checkParMeasurement(v, knownitem)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.models.KnownItems.Update(knownitem)
 	if err != nil {
 		switch {
@@ -169,13 +244,15 @@ func (app *application) updateKnownItemHandler(w http.ResponseWriter, r *http.Re
 }
 
 func (app *application) deleteKnownItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.KnownItems.Delete(id)
+	err = app.models.KnownItems.Delete(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -193,6 +270,8 @@ func (app *application) deleteKnownItemHandler(w http.ResponseWriter, r *http.Re
 }
 
 func (app *application) listKnownItemsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		SerialNumber  int
 		LongName      string
@@ -228,7 +307,7 @@ func (app *application) listKnownItemsHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	knownitems, metadata, err := app.models.KnownItems.GetAll(input.SerialNumber, input.LongName, input.ShortName, input.Tags, input.ItemType, input.Measurement, input.ContainerSize, input.Filters)
+	knownitems, metadata, err := app.models.KnownItems.GetAll(user.HouseholdID, input.SerialNumber, input.LongName, input.ShortName, input.Tags, input.ItemType, input.Measurement, input.ContainerSize, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -240,3 +319,15 @@ func (app *application) listKnownItemsHandler(w http.ResponseWriter, r *http.Req
 	}
 
 }
+
+// checkParMeasurement makes sure stock kept in the item's measurement can be
+// compared with a par level given in another one.
+func (app *application) checkParMeasurement(v *validator.Validator, knownitem *data.KnownItem) error {
+	if knownitem.ParMeasurement == nil || *knownitem.ParMeasurement == knownitem.Measurement {
+		return nil
+	}
+
+	_, err := app.convertMeasurement(v, "par_measurement", 1, *knownitem.ParMeasurement, knownitem.Measurement)
+
+	return err
+}
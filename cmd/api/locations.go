@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+func (app *application) createLocationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name     string `json:"name"`
+		ParentID *int64 `json:"parent_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	location := &data.Location{
+		HouseholdID: user.HouseholdID,
+		Name:        input.Name,
+		ParentID:    input.ParentID,
+	}
+
+	v := validator.New()
+
+	if data.ValidateLocation(v, location); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkLocationParent(v, location)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Locations.Insert(location)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateLocation):
+			v.AddError("name", "a location with this name already exists here")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/locations/%d", location.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"location": location}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showLocationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	location, err := app.models.Locations.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"location": location}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateLocationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	location, err := app.models.Locations.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name     *string `json:"name"`
+		ParentID *int64  `json:"parent_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		location.Name = *input.Name
+	}
+
+	// A parent_id of 0 moves the location to the top level.
+	if input.ParentID != nil {
+		location.ParentID = input.ParentID
+
+		if *input.ParentID == 0 {
+			location.ParentID = nil
+		}
+	}
+
+	v := validator.New()
+
+	if data.ValidateLocation(v, location); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkLocationParent(v, location)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Locations.Update(location)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateLocation):
+			v.AddError("name", "a location with this name already exists here")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"location": location}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteLocationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Locations.Delete(id, user.HouseholdID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateLocation):
+			v := validator.New()
+			v.AddError("location", "contains a location with the same name as one in its parent, rename or move it first")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "location successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name     string
+		ParentID int
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = app.readString(qs, "name", "")
+	input.ParentID = app.readInt(qs, "parent_id", 0, v)
+
+	topLevel := app.readBool(qs, "top_level", nil, v)
+	if topLevel != nil && *topLevel {
+		input.ParentID = -1
+	}
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+
+	input.Filters.SortSafelist = []string{"id", "name", "parent_id", "-id", "-name", "-parent_id"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	locations, metadata, err := app.models.Locations.GetAll(user.HouseholdID, input.Name, input.ParentID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"locations": locations, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkLocationParent makes sure the parent belongs to the same household and,
+// for existing locations, is not nested below the location itself.
+func (app *application) checkLocationParent(v *validator.Validator, location *data.Location) error {
+	if location.ParentID == nil {
+		return nil
+	}
+
+	_, err := app.models.Locations.Get(*location.ParentID, location.HouseholdID)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		v.AddError("parent_id", "must reference an existing location")
+		return nil
+	}
+
+	if location.ID == 0 {
+		return nil
+	}
+
+	descendants, err := app.models.Locations.GetDescendantIDs(location.ID, location.HouseholdID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range descendants {
+		if id == *location.ParentID {
+			v.AddError("parent_id", "must not be nested below the location itself")
+			break
+		}
+	}
+
+	return nil
+}
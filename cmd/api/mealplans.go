@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+const maxMealPlanRecipes = 50
+
+type plannedRecipe struct {
+	RecipeID   int64   `json:"recipe_id"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+type coveredIngredient struct {
+	IngredientID int64  `json:"ingredient_id"`
+	Name         string `json:"name"`
+}
+
+// generateShoppingListHandler works out what the given recipes need, takes away
+// what is already in stock through the known items each ingredient is linked to,
+// and adds what is missing to the shopping list. Ingredients without a linked
+// known item they can be checked out as are added as free text.
+func (app *application) generateShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		RecipeID int64           `json:"recipe_id"`
+		Recipes  []plannedRecipe `json:"recipes"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.RecipeID != 0 {
+		input.Recipes = append(input.Recipes, plannedRecipe{RecipeID: input.RecipeID, Multiplier: 1})
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.Recipes) > 0, "recipes", "must contain at least one recipe")
+	v.Check(len(input.Recipes) <= maxMealPlanRecipes, "recipes", "must not contain more than 50 recipes")
+
+	recipeIDs := []int64{}
+	multipliers := []float64{}
+
+	for i := range input.Recipes {
+		planned := &input.Recipes[i]
+
+		if planned.Multiplier == 0 {
+			planned.Multiplier = 1
+		}
+
+		v.Check(planned.Multiplier > 0, "multiplier", "must be greater than zero")
+		v.Check(planned.Multiplier <= 100, "multiplier", "must not be more than 100")
+
+		recipeIDs = append(recipeIDs, planned.RecipeID)
+		multipliers = append(multipliers, planned.Multiplier)
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	for _, recipeID := range recipeIDs {
+		_, err := app.models.Recipies.Get(recipeID, user.HouseholdID)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			v.AddError("recipe_id", "must reference existing recipies")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	required, err := app.models.RecipeIngredients.GetRequired(user.HouseholdID, recipeIDs, multipliers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ingredientIDs := []int64{}
+
+	for _, ingredient := range required {
+		ingredientIDs = append(ingredientIDs, ingredient.IngredientID)
+	}
+
+	mappedStock, err := app.models.IngredientKnownItems.GetStock(user.HouseholdID, ingredientIDs, time.Now())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	items, coveredIngredients, err := data.PlanShoppingList(shoppinglist.ID, user.ID, required, mappedStock)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	covered := []coveredIngredient{}
+
+	for _, ingredient := range coveredIngredients {
+		covered = append(covered, coveredIngredient{IngredientID: ingredient.IngredientID, Name: ingredient.Name})
+	}
+
+	err = app.models.ShoppingListItems.InsertAll(items)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"items": items, "covered": covered}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/units"
 	"householdingindex.homecatalogue.net/internal/validator"
 )
 
@@ -186,3 +187,172 @@ func (app *application) listMeasurementsHandler(w http.ResponseWriter, r *http.R
 	}
 
 }
+
+type conversion struct {
+	Amount  float64 `json:"amount"`
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Density float64 `json:"density,omitempty"`
+	Result  float64 `json:"result"`
+}
+
+// convertMeasurementHandler converts an amount between two measurements. Mass
+// and volume can be converted when a density is given or the ingredient the
+// amount is for has one.
+func (app *application) convertMeasurementHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Amount       float64
+		From         int
+		To           int
+		Density      float64
+		IngredientID int
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Amount = app.readFloat(qs, "amount", 0, v)
+	input.From = app.readInt(qs, "from", 0, v)
+	input.To = app.readInt(qs, "to", 0, v)
+	input.Density = app.readFloat(qs, "density", 0, v)
+	input.IngredientID = app.readInt(qs, "ingredient_id", 0, v)
+
+	v.Check(qs.Get("amount") != "", "amount", "must be provided")
+	v.Check(input.Amount >= 0, "amount", "must not be negative")
+	v.Check(input.From > 0, "from", "must be provided")
+	v.Check(input.To > 0, "to", "must be provided")
+	v.Check(input.Density >= 0, "density", "must not be negative")
+	v.Check(input.Density <= 25, "density", "must not be more than 25 grams per milliliter")
+	v.Check(input.IngredientID >= 0, "ingredient_id", "must not be negative")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.IngredientID > 0 && input.Density == 0 {
+		permitted, err := app.hasPermission(r, "ingredients:read")
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !permitted {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		ingredient, err := app.models.Ingredients.Get(int64(input.IngredientID), user.HouseholdID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("ingredient_id", "must reference an existing ingredient")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		input.Density = ingredient.Density
+	}
+
+	measurements := make(map[string]units.Unit)
+	names := make(map[string]string)
+
+	for key, id := range map[string]int{"from": input.From, "to": input.To} {
+		measurement, err := app.models.Measurements.Get(int64(id))
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			v.AddError(key, "must reference an existing measurement")
+			continue
+		}
+
+		unit, err := units.Lookup(measurement.Name)
+		if err != nil {
+			v.AddError(key, fmt.Sprintf("%q cannot be converted", measurement.Name))
+			continue
+		}
+
+		measurements[key] = unit
+		names[key] = measurement.Name
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	result, err := units.ConvertDensity(input.Amount, measurements["from"], measurements["to"], input.Density)
+	if err != nil {
+		switch {
+		case errors.Is(err, units.ErrMissingDensity):
+			v.AddError("density", "must be provided to convert between mass and volume")
+		default:
+			v.AddError("to", fmt.Sprintf("cannot convert %s to %s", names["from"], names["to"]))
+		}
+
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	converted := conversion{
+		Amount:  input.Amount,
+		From:    names["from"],
+		To:      names["to"],
+		Density: input.Density,
+		Result:  roundAmount(result),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"conversion": converted}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// convertMeasurement converts amount between two measurements by their ids. A
+// measurement that does not exist or cannot be converted is reported on v under
+// key, with amount returned unchanged.
+func (app *application) convertMeasurement(v *validator.Validator, key string, amount float64, fromID, toID int64) (float64, error) {
+	from, err := app.models.Measurements.Get(fromID)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return amount, err
+		}
+
+		v.AddError(key, "must reference an existing measurement")
+		return amount, nil
+	}
+
+	to, err := app.models.Measurements.Get(toID)
+	if err != nil {
+		return amount, err
+	}
+
+	fromUnit, err := units.Lookup(from.Name)
+	if err != nil {
+		v.AddError(key, fmt.Sprintf("%q cannot be converted", from.Name))
+		return amount, nil
+	}
+
+	toUnit, err := units.Lookup(to.Name)
+	if err != nil {
+		v.AddError(key, fmt.Sprintf("%q cannot be converted", to.Name))
+		return amount, nil
+	}
+
+	converted, err := units.Convert(amount, fromUnit, toUnit)
+	if err != nil {
+		v.AddError(key, fmt.Sprintf("cannot convert %s to %s", from.Name, to.Name))
+		return amount, nil
+	}
+
+	return converted, nil
+}
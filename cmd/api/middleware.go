@@ -98,13 +98,16 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			app.invalidAuthenticationTokenResponse(w, r)
+		if strings.HasPrefix(authorizationHeader, "ApiKey ") {
+			app.authenticateAPIKey(next, w, r, strings.TrimPrefix(authorizationHeader, "ApiKey "))
 			return
 		}
 
-		token := headerParts[1]
+		token, err := app.readBearerToken(r)
+		if err != nil {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
 
 		v := validator.New()
 
@@ -124,12 +127,55 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		err = app.models.Tokens.UpdateLastUsed(data.ScopeAuthentication, token)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
 		r = app.contextSetUser(r, user)
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+func (app *application) authenticateAPIKey(next http.Handler, w http.ResponseWriter, r *http.Request, key string) {
+	v := validator.New()
+
+	if data.ValidateAPIKeyPlaintext(v, key); !v.Valid() {
+		app.invalidAPIKeyResponse(w, r)
+		return
+	}
+
+	apikey, err := app.models.APIKeys.GetForKey(key)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAPIKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.Get(apikey.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.APIKeys.UpdateLastUsed(apikey.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetAPIKey(r, apikey)
+
+	next.ServeHTTP(w, r)
+}
+
 func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
@@ -138,6 +184,12 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 			return
 		}
 
+		// API keys are rejected unless requirePermission checks their scope.
+		if app.contextGetAPIKey(r) != nil && !app.contextGetAPIKeyChecked(r) {
+			app.apiKeyNotPermittedResponse(w, r)
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -159,15 +211,53 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		user := app.contextGetUser(r)
-
-		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		permitted, err := app.hasPermission(r, code)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
 		}
 
-		if !permissions.Include(code) {
+		if !permitted {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	checked := app.requireActivatedUser(fn)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		checked.ServeHTTP(w, app.contextSetAPIKeyChecked(r))
+	}
+}
+
+// hasPermission reports whether the user, and the api key if the request was made
+// with one, have the permission.
+func (app *application) hasPermission(r *http.Request, code string) (bool, error) {
+	user := app.contextGetUser(r)
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if !permissions.Include(code) {
+		return false, nil
+	}
+
+	if apikey := app.contextGetAPIKey(r); apikey != nil && !apikey.Permissions.Include(code) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (app *application) requireHouseholdRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if user.HouseholdID == 0 || !data.RoleIncludes(user.HouseholdRole, role) {
 			app.notPermittedResponse(w, r)
 			return
 		}
@@ -10,6 +10,8 @@ import (
 )
 
 func (app *application) createRecipeIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		RecipeID     int64 `json:"recipe_id"`
 		IngredientID int64 `json:"ingredient_id"`
@@ -37,6 +39,17 @@ func (app *application) createRecipeIngredientHandler(w http.ResponseWriter, r *
 		return
 	}
 
+	err = app.checkRecipeIngredientReferences(v, recipeingredient, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.models.RecipeIngredients.Insert(recipeingredient)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -53,13 +66,15 @@ func (app *application) createRecipeIngredientHandler(w http.ResponseWriter, r *
 }
 
 func (app *application) showRecipeIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	recipeid, ingredientid, err := app.readRecipeIngredientIDsParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	recipeingredient, err := app.models.RecipeIngredients.Get(recipeid, ingredientid)
+	recipeingredient, err := app.models.RecipeIngredients.Get(recipeid, ingredientid, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -77,13 +92,15 @@ func (app *application) showRecipeIngredientHandler(w http.ResponseWriter, r *ht
 }
 
 func (app *application) updateRecipeIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	recipeid, ingredientid, err := app.readRecipeIngredientIDsParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	recipeingredient, err := app.models.RecipeIngredients.Get(recipeid, ingredientid)
+	recipeingredient, err := app.models.RecipeIngredients.Get(recipeid, ingredientid, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -130,7 +147,18 @@ func (app *application) updateRecipeIngredientHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	err = app.models.RecipeIngredients.Update(recipeingredient, &recipeid, &ingredientid)
+	err = app.checkRecipeIngredientReferences(v, recipeingredient, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.RecipeIngredients.Update(recipeingredient, &recipeid, &ingredientid, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -148,13 +176,15 @@ func (app *application) updateRecipeIngredientHandler(w http.ResponseWriter, r *
 }
 
 func (app *application) deleteRecipeIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	recipeid, ingredientid, err := app.readRecipeIngredientIDsParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.RecipeIngredients.Delete(recipeid, ingredientid)
+	err = app.models.RecipeIngredients.Delete(recipeid, ingredientid, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -172,6 +202,8 @@ func (app *application) deleteRecipeIngredientHandler(w http.ResponseWriter, r *
 }
 
 func (app *application) listRecipeIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		Amount      int
 		Measurement int
@@ -197,7 +229,7 @@ func (app *application) listRecipeIngredientsHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	recipeingredients, metadata, err := app.models.RecipeIngredients.GetAll(input.Amount, input.Measurement, input.Filters)
+	recipeingredients, metadata, err := app.models.RecipeIngredients.GetAll(user.HouseholdID, input.Amount, input.Measurement, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -208,3 +240,25 @@ func (app *application) listRecipeIngredientsHandler(w http.ResponseWriter, r *h
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) checkRecipeIngredientReferences(v *validator.Validator, recipeingredient *data.RecipeIngredient, householdID int64) error {
+	_, err := app.models.Recipies.Get(recipeingredient.RecipeID, householdID)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		v.AddError("recipe_id", "must reference an existing recipe")
+	}
+
+	_, err = app.models.Ingredients.Get(recipeingredient.IngredientID, householdID)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		v.AddError("ingredient_id", "must reference an existing ingredient")
+	}
+
+	return nil
+}
@@ -4,12 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/units"
 	"householdingindex.homecatalogue.net/internal/validator"
 )
 
 func (app *application) createRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		Name            string   `json:"name"`
 		Description     string   `json:"description"`
@@ -26,6 +30,7 @@ func (app *application) createRecipeHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	recipe := &data.Recipe{
+		HouseholdID:     user.HouseholdID,
 		Name:            input.Name,
 		Description:     input.Description,
 		CookingSteps:    input.CookingSteps,
@@ -57,13 +62,15 @@ func (app *application) createRecipeHandler(w http.ResponseWriter, r *http.Reque
 }
 
 func (app *application) showRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	recipe, err := app.models.Recipies.Get(id)
+	recipe, err := app.models.Recipies.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -74,20 +81,108 @@ func (app *application) showRecipeHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"recipe": recipe}, nil)
+	qs := r.URL.Query()
+
+	if qs.Get("portions") == "" {
+		err = app.writeJSON(w, http.StatusOK, envelope{"recipe": recipe}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	permitted, err := app.hasPermission(r, "recipeingredients:read")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !permitted {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	portions := app.readInt(qs, "portions", 0, v)
+
+	v.Check(portions >= 1, "portions", "must be greater than 0")
+	v.Check(portions <= 10000, "portions", "must not be greater than 10000")
+	v.Check(recipe.Portions >= 1, "portions", "cannot scale a recipe without portions")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ingredients, err := app.scaleRecipeIngredients(recipe, portions)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
+
+	recipe.Portions = int32(portions)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recipe": recipe, "ingredients": ingredients}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type scaledIngredient struct {
+	IngredientID int64   `json:"ingredient_id"`
+	Name         string  `json:"name"`
+	Amount       float64 `json:"amount"`
+	Measurement  string  `json:"measurement"`
+}
+
+// scaleRecipeIngredients returns the ingredients of the recipe for the given
+// number of portions, rounded and in the largest unit that fits. Measurements
+// that are not known units keep their name and are only rounded.
+func (app *application) scaleRecipeIngredients(recipe *data.Recipe, portions int) ([]scaledIngredient, error) {
+	multiplier := float64(portions) / float64(recipe.Portions)
+
+	required, err := app.models.RecipeIngredients.GetRequired(recipe.HouseholdID, []int64{recipe.ID}, []float64{multiplier})
+	if err != nil {
+		return nil, err
+	}
+
+	ingredients := []scaledIngredient{}
+
+	for _, ingredient := range required {
+		scaled := scaledIngredient{
+			IngredientID: ingredient.IngredientID,
+			Name:         ingredient.Name,
+			Amount:       units.Round(ingredient.Amount, units.Unit{}),
+			Measurement:  ingredient.Measurement,
+		}
+
+		unit, err := units.Lookup(ingredient.Measurement)
+		if err == nil {
+			amount, larger := units.Simplify(ingredient.Amount, unit)
+			scaled.Amount = amount
+
+			if larger.Name != unit.Name {
+				scaled.Measurement = larger.Name
+			}
+		}
+
+		ingredients = append(ingredients, scaled)
+	}
+
+	return ingredients, nil
 }
 
 func (app *application) updateRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	recipe, err := app.models.Recipies.Get(id)
+	recipe, err := app.models.Recipies.Get(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -162,13 +257,15 @@ func (app *application) updateRecipeHandler(w http.ResponseWriter, r *http.Reque
 }
 
 func (app *application) deleteRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.Recipies.Delete(id)
+	err = app.models.Recipies.Delete(id, user.HouseholdID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -186,6 +283,8 @@ func (app *application) deleteRecipeHandler(w http.ResponseWriter, r *http.Reque
 }
 
 func (app *application) listRecipiesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
 	var input struct {
 		Name            string
 		Description     string
@@ -219,7 +318,7 @@ func (app *application) listRecipiesHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	recipies, metadata, err := app.models.Recipies.GetAll(input.Name, input.Description, input.CookingSteps, input.CookTimeMinutes, input.Portions, input.Tags, input.Filters)
+	recipies, metadata, err := app.models.Recipies.GetAll(user.HouseholdID, input.Name, input.Description, input.CookingSteps, input.CookTimeMinutes, input.Portions, input.Tags, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -231,3 +330,57 @@ func (app *application) listRecipiesHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 }
+
+// listCookableRecipiesHandler lists the recipies ranked by how much of them can
+// be cooked from what is in stock right now.
+func (app *application) listCookableRecipiesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Tags          []string
+		MaxMissing    int
+		ExpiresWithin time.Duration
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Tags = app.readCSV(qs, "tags", []string{})
+
+	input.MaxMissing = -1
+
+	if qs.Get("max_missing") != "" {
+		input.MaxMissing = app.readInt(qs, "max_missing", 0, v)
+		v.Check(input.MaxMissing >= 0, "max_missing", "must not be negative")
+	}
+
+	input.ExpiresWithin = app.readDuration(qs, "expires_within", 3*24*time.Hour, v)
+	v.Check(input.ExpiresWithin >= 0, "expires_within", "must not be negative")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	// The ranking is the only order the recipies are returned in.
+	input.Filters.Sort = "rank"
+	input.Filters.SortSafelist = []string{"rank"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	now := time.Now()
+
+	recipies, metadata, err := app.models.Recipies.GetCookable(user.HouseholdID, input.Tags, input.MaxMissing, now, now.Add(input.ExpiresWithin), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recipies": recipies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/units"
+)
+
+type restockItem struct {
+	KnownItemsID int64   `json:"knownitems_id"`
+	LongName     string  `json:"long_name"`
+	ParLevel     float64 `json:"par_level"`
+	Stock        float64 `json:"stock"`
+	Shortfall    float64 `json:"shortfall"`
+	Measurement  string  `json:"measurement"`
+}
+
+// restockHandler lists the known items whose unexpired stock is below their par
+// level, with the stock and shortfall given in the par level's measurement.
+func (app *application) restockHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	levels, err := app.models.KnownItems.GetStockLevels(user.HouseholdID, time.Now())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	items := []restockItem{}
+
+	for _, level := range levels {
+		stock := float64(level.Stock)
+
+		if level.MeasurementID != level.ParMeasurementID {
+			stock, err = convertUnits(stock, level.Measurement, level.ParMeasurement, 0)
+			if err != nil {
+				// The measurements were convertible when the par level was set,
+				// but the known item's measurement may have changed since.
+				app.logger.PrintError(err, map[string]string{
+					"knownitems_id": strconv.FormatInt(level.KnownItemsID, 10),
+				})
+				continue
+			}
+		}
+
+		if stock >= level.ParLevel {
+			continue
+		}
+
+		items = append(items, restockItem{
+			KnownItemsID: level.KnownItemsID,
+			LongName:     level.LongName,
+			ParLevel:     level.ParLevel,
+			Stock:        roundAmount(stock),
+			Shortfall:    roundAmount(level.ParLevel - stock),
+			Measurement:  level.ParMeasurement,
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"restock": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// convertUnits converts an amount between two measurements by name, going between
+// mass and volume when the density in grams per milliliter is known.
+func convertUnits(amount float64, from, to string, density float64) (float64, error) {
+	fromUnit, err := units.Lookup(from)
+	if err != nil {
+		return 0, err
+	}
+
+	toUnit, err := units.Lookup(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return units.ConvertDensity(amount, fromUnit, toUnit, density)
+}
+
+// roundAmount rounds to three decimals, enough for any of the units in use.
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*1000) / 1000
+}
@@ -4,6 +4,8 @@ import (
 	"expvar"
 	"net/http"
 
+	"householdingindex.homecatalogue.net/internal/data"
+
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -15,35 +17,68 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
 
-	router.HandlerFunc(http.MethodGet, "/v1/recipies", app.requirePermission("recipies:read", app.listRecipiesHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/recipies", app.requirePermission("recipies:write", app.createRecipeHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/recipies/:id", app.requirePermission("recipies:read", app.showRecipeHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/recipies/:id", app.requirePermission("recipies:write", app.updateRecipeHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/recipies/:id", app.requirePermission("recipies:write", app.deleteRecipeHandler))
-
-	router.HandlerFunc(http.MethodGet, "/v1/ingredients", app.requirePermission("ingredients:read", app.listIngredientsHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/ingredients", app.requirePermission("ingredients:write", app.createIngredientHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/ingredients/:id", app.requirePermission("ingredients:read", app.showIngredientHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/ingredients/:id", app.requirePermission("ingredients:write", app.updateIngredientHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/ingredients/:id", app.requirePermission("ingredients:write", app.deleteIngredientHandler))
-
-	router.HandlerFunc(http.MethodGet, "/v1/recipeingredients", app.requirePermission("recipeingredients:read", app.listRecipeIngredientsHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/recipeingredients", app.requirePermission("recipeingredients:write", app.createRecipeIngredientHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/recipeingredients/:recipe_id/:ingredient_id", app.requirePermission("recipeingredients:read", app.showRecipeIngredientHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/recipeingredients/:recipe_id/:ingredient_id", app.requirePermission("recipeingredients:write", app.updateRecipeIngredientHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/recipeingredients/:recipe_id/:ingredient_id", app.requirePermission("recipeingredients:write", app.deleteRecipeIngredientHandler))
-
-	router.HandlerFunc(http.MethodGet, "/v1/availableitems", app.requirePermission("availableitems:read", app.listAvailableItemsHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/availableitems", app.requirePermission("availableitems:write", app.createAvailableItemHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/availableitems/:id", app.requirePermission("availableitems:read", app.showAvailableItemHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/availableitems/:id", app.requirePermission("availableitems:write", app.updateAvailableItemHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/availableitems/:id", app.requirePermission("availableitems:write", app.deleteAvailableItemHandler))
-
-	router.HandlerFunc(http.MethodGet, "/v1/knownitems", app.requirePermission("knownitems:read", app.listKnownItemsHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/knownitems", app.requirePermission("knownitems:write", app.createKnownItemHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/knownitems/:id", app.requirePermission("knownitems:read", app.showKnownItemHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/knownitems/:id", app.requirePermission("knownitems:write", app.updateKnownItemHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/knownitems/:id", app.requirePermission("knownitems:write", app.deleteKnownItemHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/recipies", app.requirePermission("recipies:read", app.requireHouseholdRole(data.RoleViewer, app.listRecipiesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/recipies", app.requirePermission("recipies:write", app.requireHouseholdRole(data.RoleMember, app.createRecipeHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/recipies/:id", app.whenParam("id", "cookable",
+		app.requirePermission("recipies:read", app.requirePermission("availableitems:read", app.requireHouseholdRole(data.RoleViewer, app.listCookableRecipiesHandler))),
+		app.requirePermission("recipies:read", app.requireHouseholdRole(data.RoleViewer, app.showRecipeHandler))))
+	router.HandlerFunc(http.MethodPatch, "/v1/recipies/:id", app.requirePermission("recipies:write", app.requireHouseholdRole(data.RoleMember, app.updateRecipeHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/recipies/:id", app.requirePermission("recipies:write", app.requireHouseholdRole(data.RoleMember, app.deleteRecipeHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/ingredients", app.requirePermission("ingredients:read", app.requireHouseholdRole(data.RoleViewer, app.listIngredientsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/ingredients", app.requirePermission("ingredients:write", app.requireHouseholdRole(data.RoleMember, app.createIngredientHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/ingredients/:id", app.requirePermission("ingredients:read", app.requireHouseholdRole(data.RoleViewer, app.showIngredientHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/ingredients/:id", app.requirePermission("ingredients:write", app.requireHouseholdRole(data.RoleMember, app.updateIngredientHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/ingredients/:id", app.requirePermission("ingredients:write", app.requireHouseholdRole(data.RoleMember, app.deleteIngredientHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/ingredients/:id/knownitems", app.requirePermission("ingredients:read", app.requirePermission("knownitems:read", app.requireHouseholdRole(data.RoleViewer, app.listIngredientKnownItemsHandler))))
+	router.HandlerFunc(http.MethodPost, "/v1/ingredients/:id/knownitems", app.requirePermission("ingredients:write", app.requirePermission("knownitems:read", app.requireHouseholdRole(data.RoleMember, app.createIngredientKnownItemHandler))))
+	router.HandlerFunc(http.MethodGet, "/v1/ingredients/:id/knownitems/suggestions", app.requirePermission("ingredients:read", app.requirePermission("knownitems:read", app.requireHouseholdRole(data.RoleViewer, app.suggestIngredientKnownItemsHandler))))
+	router.HandlerFunc(http.MethodDelete, "/v1/ingredients/:id/knownitems/:knownitems_id", app.requirePermission("ingredients:write", app.requireHouseholdRole(data.RoleMember, app.deleteIngredientKnownItemHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/recipeingredients", app.requirePermission("recipeingredients:read", app.requireHouseholdRole(data.RoleViewer, app.listRecipeIngredientsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/recipeingredients", app.requirePermission("recipeingredients:write", app.requireHouseholdRole(data.RoleMember, app.createRecipeIngredientHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/recipeingredients/:recipe_id/:ingredient_id", app.requirePermission("recipeingredients:read", app.requireHouseholdRole(data.RoleViewer, app.showRecipeIngredientHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/recipeingredients/:recipe_id/:ingredient_id", app.requirePermission("recipeingredients:write", app.requireHouseholdRole(data.RoleMember, app.updateRecipeIngredientHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/recipeingredients/:recipe_id/:ingredient_id", app.requirePermission("recipeingredients:write", app.requireHouseholdRole(data.RoleMember, app.deleteRecipeIngredientHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/availableitems", app.requirePermission("availableitems:read", app.requireHouseholdRole(data.RoleViewer, app.listAvailableItemsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/availableitems", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.createAvailableItemHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/availableitems/:id", app.requirePermission("availableitems:read", app.requireHouseholdRole(data.RoleViewer, app.showAvailableItemHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/availableitems/:id", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.updateAvailableItemHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/availableitems/:id", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.deleteAvailableItemHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/availableitems/:id/consume", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.consumeAvailableItemHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/availableitems/:id/open", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.openAvailableItemHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/availableitems/:id/freeze", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.freezeAvailableItemHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/availableitems/:id/move", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.moveAvailableItemHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/availableitems/:id/history", app.requirePermission("availableitems:read", app.requireHouseholdRole(data.RoleViewer, app.availableItemHistoryHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/stockmovements", app.requirePermission("availableitems:read", app.requireHouseholdRole(data.RoleViewer, app.listStockMovementsHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/shoppinglists", app.requirePermission("shoppinglists:read", app.requireHouseholdRole(data.RoleViewer, app.listShoppingListsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/shoppinglists", app.requirePermission("shoppinglists:write", app.requireHouseholdRole(data.RoleMember, app.createShoppingListHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/shoppinglists/:id", app.requirePermission("shoppinglists:read", app.requireHouseholdRole(data.RoleViewer, app.showShoppingListHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/shoppinglists/:id", app.requirePermission("shoppinglists:write", app.requireHouseholdRole(data.RoleMember, app.updateShoppingListHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/shoppinglists/:id", app.requirePermission("shoppinglists:write", app.requireHouseholdRole(data.RoleMember, app.deleteShoppingListHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/shoppinglists/:id/checkout", app.requirePermission("shoppinglists:write", app.requirePermission("availableitems:write", app.requireHouseholdRole(data.RoleMember, app.checkoutShoppingListHandler))))
+	router.HandlerFunc(http.MethodPost, "/v1/shoppinglists/:id/generate", app.requirePermission("shoppinglists:write", app.requirePermission("recipies:read", app.requireHouseholdRole(data.RoleMember, app.generateShoppingListHandler))))
+	router.HandlerFunc(http.MethodGet, "/v1/shoppinglists/:id/items", app.requirePermission("shoppinglists:read", app.requireHouseholdRole(data.RoleViewer, app.listShoppingListItemsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/shoppinglists/:id/items", app.requirePermission("shoppinglists:write", app.requireHouseholdRole(data.RoleMember, app.createShoppingListItemHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/shoppinglists/:id/items/:item_id", app.requirePermission("shoppinglists:write", app.requireHouseholdRole(data.RoleMember, app.updateShoppingListItemHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/shoppinglists/:id/items/:item_id", app.requirePermission("shoppinglists:write", app.requireHouseholdRole(data.RoleMember, app.deleteShoppingListItemHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/restock", app.requirePermission("availableitems:read", app.requireHouseholdRole(data.RoleViewer, app.restockHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/locations", app.requirePermission("locations:read", app.requireHouseholdRole(data.RoleViewer, app.listLocationsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/locations", app.requirePermission("locations:write", app.requireHouseholdRole(data.RoleMember, app.createLocationHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/locations/:id", app.requirePermission("locations:read", app.requireHouseholdRole(data.RoleViewer, app.showLocationHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/locations/:id", app.requirePermission("locations:write", app.requireHouseholdRole(data.RoleMember, app.updateLocationHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/locations/:id", app.requirePermission("locations:write", app.requireHouseholdRole(data.RoleMember, app.deleteLocationHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/knownitems", app.requirePermission("knownitems:read", app.requireHouseholdRole(data.RoleViewer, app.listKnownItemsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/knownitems", app.requirePermission("knownitems:write", app.requireHouseholdRole(data.RoleMember, app.createKnownItemHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/knownitems/:id", app.requirePermission("knownitems:read", app.requireHouseholdRole(data.RoleViewer, app.showKnownItemHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/knownitems/:id", app.requirePermission("knownitems:write", app.requireHouseholdRole(data.RoleMember, app.updateKnownItemHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/knownitems/:id", app.requirePermission("knownitems:write", app.requireHouseholdRole(data.RoleMember, app.deleteKnownItemHandler)))
 
 	router.HandlerFunc(http.MethodGet, "/v1/itemtypes", app.requirePermission("itemtypes:read", app.listItemTypesHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/itemtypes", app.requirePermission("itemtypes:write", app.createItemTypeHandler))
@@ -53,7 +88,9 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodGet, "/v1/measurements", app.requirePermission("measurements:read", app.listMeasurementsHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/measurements", app.requirePermission("measurements:write", app.createMeasurementHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/measurements/:id", app.requirePermission("measurements:read", app.showMeasurementHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/measurements/:id", app.whenParam("id", "convert",
+		app.requirePermission("measurements:read", app.convertMeasurementHandler),
+		app.requirePermission("measurements:read", app.showMeasurementHandler)))
 	router.HandlerFunc(http.MethodPatch, "/v1/measurements/:id", app.requirePermission("measurements:write", app.updateMeasurementHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/measurements/:id", app.requirePermission("measurements:write", app.deleteMeasurementHandler))
 
@@ -63,13 +100,71 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodPatch, "/v1/tags/:id", app.requirePermission("tags:write", app.updateTagHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/tags/:id", app.requirePermission("tags:write", app.deleteTagHandler))
 
+	router.HandlerFunc(http.MethodGet, "/v1/permissions", app.requirePermission("permissions:admin", app.listPermissionsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/roles", app.requirePermission("permissions:admin", app.listRolesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/roles", app.requirePermission("permissions:admin", app.createRoleHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/roles/:id", app.requirePermission("permissions:admin", app.showRoleHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/roles/:id", app.requirePermission("permissions:admin", app.updateRoleHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/roles/:id", app.requirePermission("permissions:admin", app.deleteRoleHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/households/:id", app.requireHouseholdRole(data.RoleViewer, app.showHouseholdHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/households/:id", app.requireHouseholdRole(data.RoleOwner, app.updateHouseholdHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/households/:id/invitations", app.requireHouseholdRole(data.RoleOwner, app.createHouseholdInvitationHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/households/:id/invitations/accepted", app.acceptHouseholdInvitationHandler)
+
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 
-	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	// The "me", "activated" and "password" routes share their place with the user
+	// id of the admin routes for the methods both use, so they are registered on
+	// the wildcard.
+	router.HandlerFunc(http.MethodPut, "/v1/users/:id", app.whenParam("id", "activated", app.activateUserHandler,
+		app.whenParam("id", "password", app.updateUserPasswordHandler, app.notFoundResponse)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id", app.whenParam("id", "me", app.requireAuthenticatedUser(app.showCurrentUserHandler), app.notFoundResponse))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me", app.requireAuthenticatedUser(app.updateCurrentUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:id", app.whenParam("id", "me", app.requireAuthenticatedUser(app.deleteCurrentUserHandler), app.notFoundResponse))
+	router.HandlerFunc(http.MethodPut, "/v1/users/:id/restored", app.whenParam("id", "me", app.requireAuthenticatedUser(app.restoreCurrentUserHandler), app.notFoundResponse))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/export", app.whenParam("id", "me", app.requireAuthenticatedUser(app.exportCurrentUserHandler), app.notFoundResponse))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/expiryalerts", app.whenParam("id", "me", app.requireActivatedUser(app.showExpiryAlertSettingsHandler), app.notFoundResponse))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/expiryalerts", app.requireActivatedUser(app.updateExpiryAlertSettingsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/totp", app.requireActivatedUser(app.createTOTPHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/:id/totp/confirmed", app.whenParam("id", "me", app.requireActivatedUser(app.confirmTOTPHandler), app.notFoundResponse))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:id/totp", app.whenParam("id", "me", app.requireActivatedUser(app.deleteTOTPHandler), app.notFoundResponse))
+
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/permissions", app.requirePermission("permissions:admin", app.showUserPermissionsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/:id/permissions", app.requirePermission("permissions:admin", app.updateUserPermissionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:id/permissions", app.requirePermission("permissions:admin", app.deleteUserPermissionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:id/lockout", app.requirePermission("permissions:admin", app.unlockUserHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/tokens/authentication", app.requireAuthenticatedUser(app.listAuthenticationTokensHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/tokens/authentication", app.requireAuthenticatedUser(app.deleteAuthenticationTokenHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/tokens/authentication/all", app.requireAuthenticatedUser(app.deleteAllAuthenticationTokensHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/refresh", app.createRefreshedAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/activation", app.createActivationTokenHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/apikeys", app.requireActivatedUser(app.listAPIKeysHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/apikeys", app.requireActivatedUser(app.createAPIKeyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/apikeys/:id", app.requireActivatedUser(app.deleteAPIKeyHandler))
 
 	router.HandlerFunc(http.MethodGet, "/debug/vars", app.requirePermission("metrics:view", expvar.Handler().ServeHTTP))
 
 	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
 }
+
+// whenParam serves match when the named path parameter equals value and other
+// otherwise. httprouter does not allow a static path segment in the same place as
+// a wildcard, so routes like /v1/recipies/cookable are registered on the wildcard
+// route and picked out here.
+func (app *application) whenParam(name, value string, match, other http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if httprouter.ParamsFromContext(r.Context()).ByName(name) == value {
+			match(w, r)
+			return
+		}
+
+		other(w, r)
+	}
+}
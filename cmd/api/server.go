@@ -39,6 +39,8 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		close(app.shutdown)
+
 		app.logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr})
 
@@ -47,6 +49,9 @@ func (app *application) serve() error {
 		shutdownError <- nil
 	}()
 
+	app.schedule(time.Hour, app.purgeDeletedAccounts)
+	app.schedule(15*time.Minute, app.sendExpiryAlerts)
+
 	app.logger.PrintInfo("starting server", map[string]string{
 		"addr": srv.Addr,
 		"env":  app.config.env,
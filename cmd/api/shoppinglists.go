@@ -0,0 +1,592 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+func (app *application) createShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	shoppinglist := &data.ShoppingList{
+		HouseholdID: user.HouseholdID,
+		Name:        input.Name,
+	}
+
+	v := validator.New()
+
+	if data.ValidateShoppingList(v, shoppinglist); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ShoppingLists.Insert(shoppinglist)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/shoppinglists/%d", shoppinglist.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"shoppinglist": shoppinglist}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shoppinglist": shoppinglist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		shoppinglist.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	if data.ValidateShoppingList(v, shoppinglist); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ShoppingLists.Update(shoppinglist)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shoppinglist": shoppinglist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.ShoppingLists.Delete(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "shopping list successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listShoppingListsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = app.readString(qs, "name", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+
+	input.Filters.SortSafelist = []string{"id", "name", "created_at", "-id", "-name", "-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	shoppinglists, metadata, err := app.models.ShoppingLists.GetAll(user.HouseholdID, input.Name, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shoppinglists": shoppinglists, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) checkoutShoppingListHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		LocationID   *int64    `json:"location_id"`
+		ExpirationAt time.Time `json:"expiration_at"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil && !errors.Is(err, errEmptyBody) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(shoppinglist.CheckedCount > 0, "shoppinglist", "must have checked items to check out")
+
+	if input.LocationID != nil {
+		_, err = app.models.Locations.Get(*input.LocationID, user.HouseholdID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("location_id", "must reference an existing location")
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	availableitems, err := app.models.ShoppingLists.Checkout(shoppinglist, user.ID, input.LocationID, input.ExpirationAt, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnconvertibleQuantity), errors.Is(err, data.ErrInvalidCheckoutItem):
+			v.AddError("items", err.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"availableitems": availableitems}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createShoppingListItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		KnownItemsID *int64  `json:"knownitems_id"`
+		Text         string  `json:"text"`
+		Quantity     float64 `json:"quantity"`
+		Measurement  *int64  `json:"measurement"`
+		Checked      bool    `json:"checked"`
+	}
+
+	input.Quantity = 1
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	item := &data.ShoppingListItem{
+		ShoppingListID: shoppinglist.ID,
+		KnownItemsID:   input.KnownItemsID,
+		Text:           input.Text,
+		Quantity:       input.Quantity,
+		Measurement:    input.Measurement,
+		Checked:        input.Checked,
+		UserID:         &user.ID,
+	}
+
+	v := validator.New()
+
+	if data.ValidateShoppingListItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkShoppingListItemReferences(v, item, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ShoppingListItems.Insert(item)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/shoppinglists/%d/items/%d", shoppinglist.ID, item.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"item": item}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateShoppingListItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	shoppinglistID, itemID, err := app.readShoppingListItemIDsParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(shoppinglistID, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	item, err := app.models.ShoppingListItems.Get(itemID, shoppinglist.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		KnownItemsID *int64   `json:"knownitems_id"`
+		Text         *string  `json:"text"`
+		Quantity     *float64 `json:"quantity"`
+		Measurement  *int64   `json:"measurement"`
+		Checked      *bool    `json:"checked"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// A knownitems_id or measurement of 0 clears it.
+	if input.KnownItemsID != nil {
+		item.KnownItemsID = input.KnownItemsID
+
+		if *input.KnownItemsID == 0 {
+			item.KnownItemsID = nil
+		}
+	}
+
+	if input.Text != nil {
+		item.Text = *input.Text
+	}
+
+	if input.Quantity != nil {
+		item.Quantity = *input.Quantity
+	}
+
+	if input.Measurement != nil {
+		item.Measurement = input.Measurement
+
+		if *input.Measurement == 0 {
+			item.Measurement = nil
+		}
+	}
+
+	if input.Checked != nil {
+		item.Checked = *input.Checked
+	}
+
+	v := validator.New()
+
+	if data.ValidateShoppingListItem(v, item); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkShoppingListItemReferences(v, item, user.HouseholdID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ShoppingListItems.Update(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteShoppingListItemHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	shoppinglistID, itemID, err := app.readShoppingListItemIDsParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(shoppinglistID, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.ShoppingListItems.Delete(itemID, shoppinglist.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "shopping list item successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listShoppingListItemsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	shoppinglist, err := app.models.ShoppingLists.Get(id, user.HouseholdID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		KnownItemsID int
+		Checked      *bool
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.KnownItemsID = app.readInt(qs, "knownitems_id", 0, v)
+	input.Checked = app.readBool(qs, "checked", nil, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+
+	input.Filters.SortSafelist = []string{"id", "checked", "created_at", "-id", "-checked", "-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	items, metadata, err := app.models.ShoppingListItems.GetAll(shoppinglist.ID, input.KnownItemsID, input.Checked, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"items": items, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkShoppingListItemReferences makes sure the known item belongs to the
+// household and that a quantity given in another measurement can be converted to
+// the known item's when the list is checked out.
+func (app *application) checkShoppingListItemReferences(v *validator.Validator, item *data.ShoppingListItem, householdID int64) error {
+	if item.KnownItemsID == nil {
+		if item.Measurement == nil {
+			return nil
+		}
+
+		_, err := app.models.Measurements.Get(*item.Measurement)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				return err
+			}
+
+			v.AddError("measurement", "must reference an existing measurement")
+		}
+
+		return nil
+	}
+
+	knownitem, err := app.models.KnownItems.Get(*item.KnownItemsID, householdID)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		v.AddError("knownitems_id", "must reference an existing known item")
+		return nil
+	}
+
+	if item.Measurement == nil || *item.Measurement == knownitem.Measurement {
+		return nil
+	}
+
+	_, err = app.convertMeasurement(v, "measurement", item.Quantity, *item.Measurement, knownitem.Measurement)
+
+	return err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+var stockMovementSortSafelist = []string{"id", "created_at", "kind", "quantity_change", "-id", "-created_at", "-kind", "-quantity_change"}
+
+// availableItemHistoryHandler lists the movements of a single item. The ledger
+// outlives the item, so the history of consumed or discarded items can still
+// be looked up.
+func (app *application) availableItemHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+
+	input.Filters.SortSafelist = stockMovementSortSafelist
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movements, metadata, err := app.models.StockMovements.GetAll(user.HouseholdID, int(id), 0, 0, "", time.Time{}, time.Time{}, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if metadata.TotalRecords == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stock_movements": movements, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listStockMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		AvailableItemsID int
+		KnownItemsID     int
+		UserID           int
+		Kind             string
+		Since            time.Time
+		Until            time.Time
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.AvailableItemsID = app.readInt(qs, "availableitems_id", 0, v)
+	input.KnownItemsID = app.readInt(qs, "knownitems_id", 0, v)
+	input.UserID = app.readInt(qs, "user_id", 0, v)
+	input.Kind = app.readString(qs, "kind", "")
+
+	input.Since = app.readTime(qs, "since", time.Time{}, v)
+	input.Until = app.readTime(qs, "until", time.Time{}, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+
+	input.Filters.SortSafelist = stockMovementSortSafelist
+
+	if input.Kind != "" {
+		data.ValidateStockMovementKind(v, input.Kind)
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movements, metadata, err := app.models.StockMovements.GetAll(user.HouseholdID, input.AvailableItemsID, input.KnownItemsID, input.UserID, input.Kind, input.Since, input.Until, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stock_movements": movements, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
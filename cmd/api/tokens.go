@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"householdingindex.homecatalogue.net/internal/data"
@@ -11,8 +14,10 @@ import (
 
 func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email        string `json:"email"`
+		Password     string `json:"password"`
+		TOTPCode     string `json:"totp_code"`
+		RecoveryCode string `json:"recovery_code"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -48,18 +53,324 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	if !match {
+	// A locked account gets the same response as a wrong password, only its owner
+	// learns about the lock through the email sent when it was locked. The password
+	// is still checked so the response takes as long either way.
+	if user.IsLocked() {
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	if !match {
+		app.failedLoginResponse(w, r, user)
+		return
+	}
+
+	if !app.checkSecondFactor(w, r, user, input.TOTPCode, input.RecoveryCode) {
+		return
+	}
+
+	err = app.models.Users.ResetFailedLogins(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, refreshToken, err := app.models.Tokens.NewSession(user.ID, nil, app.config.tokens.authenticationTTL, app.config.tokens.refreshTTL, r.UserAgent())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token, "refresh_token": refreshToken}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// failedLoginResponse counts a failed login against the user and sends the
+// invalid credentials response.
+func (app *application) failedLoginResponse(w http.ResponseWriter, r *http.Request, user *data.User) {
+	err := app.recordFailedLogin(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidCredentialsResponse(w, r)
+}
+
+// recordFailedLogin counts a failed login against the user and emails them if the
+// account got locked as a result.
+func (app *application) recordFailedLogin(user *data.User) error {
+	lockedUntil, err := app.models.Users.RecordFailedLogin(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if lockedUntil != nil {
+		app.logger.PrintInfo("account locked after failed logins", map[string]string{
+			"user_id":      strconv.FormatInt(user.ID, 10),
+			"locked_until": lockedUntil.Format(time.RFC3339),
+		})
+
+		app.background(func() {
+			data := map[string]interface{}{
+				"name":        user.Name,
+				"lockedUntil": lockedUntil.UTC().Format(time.RFC1123),
+			}
+
+			err := app.mailer.Send(user.Email, "account_locked.tmpl", data)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		})
+	}
+
+	return nil
+}
+
+func (app *application) createRefreshedAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.RefreshToken); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	previous, err := app.models.Tokens.Rotate(data.ScopeRefresh, input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidRefreshTokenResponse(w, r)
+		case errors.Is(err, data.ErrTokenReused):
+			app.logger.PrintInfo("refresh token reused, revoking token family", map[string]string{
+				"user_id": strconv.FormatInt(previous.UserID, 10),
+			})
+
+			err = app.models.Tokens.DeleteFamily(previous.Family)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			app.invalidRefreshTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	token, refreshToken, err := app.models.Tokens.NewSession(previous.UserID, previous.Family, app.config.tokens.authenticationTTL, app.config.tokens.refreshTTL, r.UserAgent())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token, "refresh_token": refreshToken}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The response must not reveal whether the email belongs to an account, so
+	// unknown and already activated emails are answered the same way, and the
+	// token is only created once the response has been sent.
+	if user != nil && !user.Activated {
+		app.background(func() {
+			err := app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				return
+			}
+
+			token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				return
+			}
+
+			data := map[string]interface{}{
+				"activationToken": token.Plaintext,
+				"userID":          user.ID,
+			}
+
+			err = app.mailer.Send(user.Email, "user_welcome.tmpl", data)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		})
+	}
+
+	env := envelope{"message": "if the email belongs to an account that is not activated yet, an email will be sent to it containing activation instructions"}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// As with activation, unknown and not yet activated emails get the same
+	// response, so that it does not reveal which accounts exist.
+	if user != nil && user.Activated {
+		app.background(func() {
+			token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				return
+			}
+
+			data := map[string]interface{}{
+				"passwordResetToken": token.Plaintext,
+			}
+
+			err = app.mailer.Send(user.Email, "token_password_reset.tmpl", data)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		})
+	}
+
+	env := envelope{"message": "if the email belongs to an activated account, an email will be sent to it containing password reset instructions"}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	current, err := app.readBearerToken(r)
+	if err != nil {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	currentHash := sha256.Sum256([]byte(current))
+
+	tokens, err := app.models.Tokens.GetAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	type session struct {
+		*data.Token
+		Current bool `json:"current"`
+	}
+
+	sessions := []session{}
+
+	for _, token := range tokens {
+		sessions = append(sessions, session{Token: token, Current: bytes.Equal(token.Hash, currentHash[:])})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"sessions": sessions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := app.readBearerToken(r)
+	if err != nil {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	err = app.models.Tokens.Delete(data.ScopeAuthentication, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "authentication token successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAllAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	err := app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeRefresh, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "all authentication tokens successfully revoked"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/data"
+	"householdingindex.homecatalogue.net/internal/totp"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+const totpIssuer = "Homecatalogue"
+
+// totpEligible reports whether the permissions are sensitive enough for the
+// account to enrol in two-factor authentication.
+func totpEligible(permissions data.Permissions) bool {
+	for _, code := range permissions {
+		if code == "metrics:view" || strings.HasSuffix(code, ":write") {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *application) createTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !totpEligible(permissions) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.TOTP.SetPending(user.ID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			v := validator.New()
+			v.AddError("totp", "is already enabled for this account")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"totp": map[string]string{
+		"secret": secret,
+		"uri":    totp.URI(totpIssuer, user.Email, secret),
+	}}
+
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTOTPCode(v, input.Code); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	t, err := app.models.TOTP.Get(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	switch {
+	case t.Enabled:
+		v.AddError("totp", "is already enabled for this account")
+	case t.Secret == "":
+		v.AddError("totp", "must be enrolled before it can be confirmed")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	step, ok := totp.Validate(t.Secret, input.Code, time.Now())
+	if !ok {
+		v.AddError("code", "invalid or expired code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	codes, err := app.models.TOTP.Enable(user.ID, step)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Password     string `json:"password"`
+		TOTPCode     string `json:"totp_code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidatePasswordPlaintext(v, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Wrong passwords count towards the login lockout, so this cannot be used to
+	// guess the password instead.
+	if user.IsLocked() {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	if !match {
+		app.failedLoginResponse(w, r, user)
+		return
+	}
+
+	// A stolen password alone must not be enough to turn the second factor off.
+	if !app.checkSecondFactor(w, r, user, input.TOTPCode, input.RecoveryCode) {
+		return
+	}
+
+	err = app.models.TOTP.Disable(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "two-factor authentication successfully disabled"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkSecondFactor verifies the TOTP or recovery code given at login, or when
+// turning two-factor authentication off, for users that have enrolled, and
+// reports whether the request may go ahead. It writes the error response itself
+// when it may not.
+func (app *application) checkSecondFactor(w http.ResponseWriter, r *http.Request, user *data.User, code, recoveryCode string) bool {
+	t, err := app.models.TOTP.Get(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !t.Enabled {
+		return true
+	}
+
+	var ok bool
+
+	switch {
+	case code != "":
+		var step int64
+
+		step, ok = totp.ValidateAfter(t.Secret, code, time.Now(), t.LastStep)
+		if ok {
+			ok, err = app.models.TOTP.UseStep(user.ID, step)
+		}
+	case recoveryCode != "":
+		ok, err = app.models.TOTP.UseRecoveryCode(user.ID, recoveryCode)
+	default:
+		app.totpRequiredResponse(w, r)
+		return false
+	}
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !ok {
+		app.failedLoginResponse(w, r, user)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,272 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+type APIKey struct {
+	ID          int64       `json:"id"`
+	UserID      int64       `json:"-"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Name        string      `json:"name"`
+	Plaintext   string      `json:"key,omitempty"`
+	Prefix      string      `json:"prefix"`
+	Hash        []byte      `json:"-"`
+	Permissions Permissions `json:"permissions"`
+	Expiry      *time.Time  `json:"expiry,omitempty"`
+	LastUsedAt  *time.Time  `json:"last_used_at,omitempty"`
+}
+
+func generateAPIKey(userID int64, name string, permissions Permissions, expiry *time.Time) (*APIKey, error) {
+	apikey := &APIKey{
+		UserID:      userID,
+		CreatedAt:   time.Now(),
+		Name:        name,
+		Permissions: permissions,
+		Expiry:      expiry,
+	}
+
+	randomBytes := make([]byte, 32)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	apikey.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	apikey.Prefix = apikey.Plaintext[:8]
+
+	hash := sha256.Sum256([]byte(apikey.Plaintext))
+	apikey.Hash = hash[:]
+
+	return apikey, nil
+}
+
+func ValidateAPIKeyPlaintext(v *validator.Validator, keyPlaintext string) {
+	v.Check(keyPlaintext != "", "key", "must be provided")
+	v.Check(len(keyPlaintext) == 52, "key", "must be 52 bytes long")
+}
+
+func ValidateAPIKey(v *validator.Validator, apikey *APIKey, granted Permissions) {
+	v.Check(apikey.Name != "", "name", "must be provided")
+	v.Check(len(apikey.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(apikey.Permissions) >= 1, "permissions", "must contain at least 1 permission")
+	v.Check(validator.Unique(apikey.Permissions), "permissions", "must not contain duplicate values")
+
+	for _, code := range apikey.Permissions {
+		v.Check(granted.Include(code), "permissions", "must only contain permissions granted to the user")
+	}
+
+	if apikey.Expiry != nil {
+		v.Check(apikey.Expiry.After(time.Now()), "expiry", "must be in the future")
+	}
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+func (m APIKeyModel) New(userID int64, name string, permissions Permissions, expiry *time.Time) (*APIKey, error) {
+	apikey, err := generateAPIKey(userID, name, permissions, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(apikey)
+
+	return apikey, err
+}
+
+func (m APIKeyModel) Insert(apikey *APIKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO api_keys (user_id, created_at, name, prefix, hash, expiry)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	args := []interface{}{apikey.UserID, apikey.CreatedAt, apikey.Name, apikey.Prefix, apikey.Hash, apikey.Expiry}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&apikey.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO api_keys_permissions
+		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
+
+	_, err = tx.ExecContext(ctx, query, apikey.ID, pq.Array(apikey.Permissions))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m APIKeyModel) GetAllForUser(userID int64) ([]*APIKey, error) {
+	query := `
+		SELECT api_keys.id, api_keys.user_id, api_keys.created_at, api_keys.name, api_keys.prefix, api_keys.expiry, api_keys.last_used_at,
+		array_remove(array_agg(permissions.code ORDER BY permissions.code), NULL)
+		FROM api_keys
+		LEFT JOIN api_keys_permissions ON api_keys_permissions.api_key_id = api_keys.id
+		LEFT JOIN permissions ON api_keys_permissions.permission_id = permissions.id
+		WHERE api_keys.user_id = $1
+		GROUP BY api_keys.id
+		ORDER BY api_keys.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	apikeys := []*APIKey{}
+
+	for rows.Next() {
+		var apikey APIKey
+
+		err := rows.Scan(
+			&apikey.ID,
+			&apikey.UserID,
+			&apikey.CreatedAt,
+			&apikey.Name,
+			&apikey.Prefix,
+			&apikey.Expiry,
+			&apikey.LastUsedAt,
+			pq.Array(&apikey.Permissions),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		apikeys = append(apikeys, &apikey)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return apikeys, nil
+}
+
+func (m APIKeyModel) GetForKey(keyPlaintext string) (*APIKey, error) {
+	keyHash := sha256.Sum256([]byte(keyPlaintext))
+
+	query := `
+		SELECT api_keys.id, api_keys.user_id, api_keys.created_at, api_keys.name, api_keys.prefix, api_keys.expiry, api_keys.last_used_at,
+		array_remove(array_agg(permissions.code ORDER BY permissions.code), NULL)
+		FROM api_keys
+		LEFT JOIN api_keys_permissions ON api_keys_permissions.api_key_id = api_keys.id
+		LEFT JOIN permissions ON api_keys_permissions.permission_id = permissions.id
+		WHERE api_keys.hash = $1
+		AND (api_keys.expiry > $2 OR api_keys.expiry IS NULL)
+		GROUP BY api_keys.id`
+
+	var apikey APIKey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, keyHash[:], time.Now()).Scan(
+		&apikey.ID,
+		&apikey.UserID,
+		&apikey.CreatedAt,
+		&apikey.Name,
+		&apikey.Prefix,
+		&apikey.Expiry,
+		&apikey.LastUsedAt,
+		pq.Array(&apikey.Permissions),
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &apikey, nil
+}
+
+func (m APIKeyModel) UpdateLastUsed(id int64) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+		AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+
+	return err
+}
+
+func (m APIKeyModel) Delete(id int64, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM api_keys
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m APIKeyModel) DeleteAllForUser(userID int64) error {
+	query := `
+		DELETE FROM api_keys
+		WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+
+	return err
+}
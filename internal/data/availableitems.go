@@ -15,29 +15,52 @@ type AvailableItemModel struct {
 	DB *sql.DB
 }
 
-func (ai AvailableItemModel) Insert(availableitem *AvailableItem) error {
+func (ai AvailableItemModel) Insert(availableitem *AvailableItem, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := ai.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = insertAvailableItem(ctx, tx, availableitem, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertAvailableItem adds the item and records it in the stock ledger as part
+// of tx.
+func insertAvailableItem(ctx context.Context, tx *sql.Tx, availableitem *AvailableItem, userID int64) error {
 	query := `
-		INSERT INTO availableitems (knownitems_id, expiration_at, container_size)
-		VALUES ($1, $2, $3)
+		INSERT INTO availableitems (household_id, knownitems_id, expiration_at, container_size, remaining, location_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at, version`
 
-	args := []interface{}{availableitem.KnownItemsID, availableitem.ExpirationAt, availableitem.ContainerSize}
+	args := []interface{}{availableitem.HouseholdID, availableitem.KnownItemsID, availableitem.ExpirationAt, availableitem.ContainerSize, availableitem.Remaining, availableitem.LocationID}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&availableitem.ID, &availableitem.CreatedAt, &availableitem.Version)
+	if err != nil {
+		return err
+	}
 
-	return ai.DB.QueryRowContext(ctx, query, args...).Scan(&availableitem.ID, &availableitem.CreatedAt, &availableitem.Version)
+	return insertStockMovement(ctx, tx, newStockMovement(availableitem, userID, MovementAdd, availableitem.Remaining))
 }
 
-func (ai AvailableItemModel) Get(id int64) (*AvailableItem, error) {
+func (ai AvailableItemModel) Get(id int64, householdID int64) (*AvailableItem, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, knownitems_id, created_at, expiration_at, container_size, version
+		SELECT id, household_id, knownitems_id, location_id, created_at, expiration_at, opened_at, frozen_at, container_size, remaining, version
 		FROM availableitems
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2`
 
 	var availableitem AvailableItem
 
@@ -45,12 +68,17 @@ func (ai AvailableItemModel) Get(id int64) (*AvailableItem, error) {
 
 	defer cancel()
 
-	err := ai.DB.QueryRowContext(ctx, query, id).Scan(
+	err := ai.DB.QueryRowContext(ctx, query, id, householdID).Scan(
 		&availableitem.ID,
+		&availableitem.HouseholdID,
 		&availableitem.KnownItemsID,
+		&availableitem.LocationID,
 		&availableitem.CreatedAt,
 		&availableitem.ExpirationAt,
+		&availableitem.OpenedAt,
+		&availableitem.FrozenAt,
 		&availableitem.ContainerSize,
+		&availableitem.Remaining,
 		&availableitem.Version,
 	)
 
@@ -66,21 +94,36 @@ func (ai AvailableItemModel) Get(id int64) (*AvailableItem, error) {
 	return &availableitem, nil
 }
 
-func (ai AvailableItemModel) GetAll(knownitemsid int, expirationat time.Time, containersize int, filters Filters) ([]*AvailableItem, Metadata, error) {
+func (ai AvailableItemModel) GetAll(householdID int64, knownitemsid int, locationid int, expirationat time.Time, expiresbefore time.Time, expired *bool, containersize int, filters Filters) ([]*AvailableItem, Metadata, error) {
 	//expiration_at currently retrieves items larger than the input ====> search for items that are still fresh according to current date
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, knownitems_id, created_at, expiration_at, container_size, version
+		SELECT count(*) OVER(), availableitems.id, availableitems.household_id, availableitems.knownitems_id, availableitems.location_id, availableitems.created_at, availableitems.expiration_at, availableitems.opened_at, availableitems.frozen_at, availableitems.container_size, availableitems.remaining, availableitems.version,
+		knownitems.long_name, COALESCE(measurements.name, ''), COALESCE(locations.name, ''), availableitems.expiration_at::date - $2::timestamptz::date
 		FROM availableitems
-		WHERE (knownitems_id = $1 OR $1 = 0)
-		AND (expiration_at >= $2 OR $2 = '0001-01-01T00:00:00Z')
-		AND (container_size = $3 OR $3 = 0)
-		ORDER BY %s %s, id ASC
-		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+		INNER JOIN knownitems ON knownitems.id = availableitems.knownitems_id
+		LEFT JOIN measurements ON measurements.id = knownitems.measurement
+		LEFT JOIN locations ON locations.id = availableitems.location_id
+		WHERE availableitems.household_id = $1
+		AND (availableitems.knownitems_id = $3 OR $3 = 0)
+		AND (availableitems.expiration_at >= $4 OR $4 = '0001-01-01T00:00:00Z')
+		AND (availableitems.expiration_at < $5 OR $5 = '0001-01-01T00:00:00Z')
+		AND ($6::boolean IS NULL OR (availableitems.expiration_at <= $2) = $6)
+		AND (availableitems.container_size = $7 OR $7 = 0)
+		AND ($8 = 0 OR availableitems.location_id IN (
+			WITH RECURSIVE descendants AS (
+				SELECT id FROM locations WHERE id = $8 AND household_id = $1
+				UNION
+				SELECT locations.id FROM locations INNER JOIN descendants ON locations.parent_id = descendants.id
+			)
+			SELECT id FROM descendants
+		))
+		ORDER BY availableitems.%s %s, availableitems.id ASC
+		LIMIT $9 OFFSET $10`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{knownitemsid, expirationat.Format(time.RFC3339), containersize, filters.limit(), filters.offset()}
+	args := []interface{}{householdID, time.Now(), knownitemsid, expirationat.Format(time.RFC3339), expiresbefore.Format(time.RFC3339), expired, containersize, locationid, filters.limit(), filters.offset()}
 
 	rows, err := ai.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -98,11 +141,20 @@ func (ai AvailableItemModel) GetAll(knownitemsid int, expirationat time.Time, co
 		err := rows.Scan(
 			&totalRecords,
 			&availableitem.ID,
+			&availableitem.HouseholdID,
 			&availableitem.KnownItemsID,
+			&availableitem.LocationID,
 			&availableitem.CreatedAt,
 			&availableitem.ExpirationAt,
+			&availableitem.OpenedAt,
+			&availableitem.FrozenAt,
 			&availableitem.ContainerSize,
+			&availableitem.Remaining,
 			&availableitem.Version,
+			&availableitem.LongName,
+			&availableitem.MeasurementName,
+			&availableitem.LocationName,
+			&availableitem.DaysRemaining,
 		)
 
 		if err != nil {
@@ -121,25 +173,43 @@ func (ai AvailableItemModel) GetAll(knownitemsid int, expirationat time.Time, co
 	return availableitems, metadata, nil
 }
 
-func (ai AvailableItemModel) Update(availableitem *AvailableItem) error {
+func (ai AvailableItemModel) Update(availableitem *AvailableItem, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := ai.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
 	query := `
+		WITH previous AS (
+			SELECT remaining
+			FROM availableitems
+			WHERE id = $5 AND household_id = $6 AND version = $7
+			FOR UPDATE
+		)
 		UPDATE availableitems
-		SET knownitems_id = $1, expiration_at = $2, container_size = $3, version = version + 1
-		WHERE id = $4 AND version = $5
-		RETURNING version`
+		SET knownitems_id = $1, expiration_at = $2, container_size = $3, remaining = $4, version = version + 1
+		FROM previous
+		WHERE id = $5 AND household_id = $6 AND version = $7
+		RETURNING version, previous.remaining`
 
 	args := []interface{}{
 		availableitem.KnownItemsID,
 		availableitem.ExpirationAt,
 		availableitem.ContainerSize,
+		availableitem.Remaining,
 		availableitem.ID,
+		availableitem.HouseholdID,
 		availableitem.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	var previousRemaining int32
 
-	err := ai.DB.QueryRowContext(ctx, query, args...).Scan(&availableitem.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&availableitem.Version, &previousRemaining)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -149,45 +219,211 @@ func (ai AvailableItemModel) Update(availableitem *AvailableItem) error {
 		}
 	}
 
-	return nil
+	if availableitem.Remaining != previousRemaining {
+		err = insertStockMovement(ctx, tx, newStockMovement(availableitem, userID, MovementAdjust, availableitem.Remaining-previousRemaining))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (ai AvailableItemModel) Delete(id int64) error {
+func (ai AvailableItemModel) Delete(id int64, householdID int64, userID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := ai.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
 	query := `
 		DELETE FROM availableitems
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2
+		RETURNING id, household_id, knownitems_id, location_id, remaining`
 
+	var availableitem AvailableItem
+
+	err = tx.QueryRowContext(ctx, query, id, householdID).Scan(
+		&availableitem.ID,
+		&availableitem.HouseholdID,
+		&availableitem.KnownItemsID,
+		&availableitem.LocationID,
+		&availableitem.Remaining,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	change := -availableitem.Remaining
+	availableitem.Remaining = 0
+
+	err = insertStockMovement(ctx, tx, newStockMovement(&availableitem, userID, MovementDiscard, change))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Consume takes amount, in the item's own measurement, out of the item's remaining
+// quantity and records who consumed it. An item that is used up is deleted, in
+// which case availableitem is left with Remaining set to zero.
+func (ai AvailableItemModel) Consume(availableitem *AvailableItem, amount int32, userID int64) (*StockMovement, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := ai.DB.ExecContext(ctx, query, id)
+	tx, err := ai.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE availableitems
+		SET remaining = remaining - $1, version = version + 1
+		WHERE id = $2 AND household_id = $3 AND version = $4 AND remaining >= $1
+		RETURNING remaining, version`
+
+	args := []interface{}{amount, availableitem.ID, availableitem.HouseholdID, availableitem.Version}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&availableitem.Remaining, &availableitem.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
+		default:
+			return nil, err
+		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	movement := newStockMovement(availableitem, userID, MovementConsume, -amount)
+
+	err = insertStockMovement(ctx, tx, movement)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	if availableitem.Remaining == 0 {
+		_, err = tx.ExecContext(ctx, `DELETE FROM availableitems WHERE id = $1`, availableitem.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return movement, tx.Commit()
+}
+
+// UpdateShelfLife saves the item's expiration together with when it was opened
+// or frozen.
+func (ai AvailableItemModel) UpdateShelfLife(availableitem *AvailableItem) error {
+	query := `
+		UPDATE availableitems
+		SET expiration_at = $1, opened_at = $2, frozen_at = $3, version = version + 1
+		WHERE id = $4 AND household_id = $5 AND version = $6
+		RETURNING version`
+
+	args := []interface{}{
+		availableitem.ExpirationAt,
+		availableitem.OpenedAt,
+		availableitem.FrozenAt,
+		availableitem.ID,
+		availableitem.HouseholdID,
+		availableitem.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ai.DB.QueryRowContext(ctx, query, args...).Scan(&availableitem.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Move puts the item in another location, or takes it out of any location when
+// locationID is nil, and records where it was moved from.
+func (ai AvailableItemModel) Move(availableitem *AvailableItem, locationID *int64, userID int64) (*StockMovement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := ai.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE availableitems
+		SET location_id = $1, version = version + 1
+		WHERE id = $2 AND household_id = $3 AND version = $4
+		RETURNING version`
+
+	args := []interface{}{locationID, availableitem.ID, availableitem.HouseholdID, availableitem.Version}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&availableitem.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+
+	from := availableitem.LocationID
+	availableitem.LocationID = locationID
+
+	movement := newStockMovement(availableitem, userID, MovementMove, 0)
+	movement.FromLocationID = from
+
+	err = insertStockMovement(ctx, tx, movement)
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, tx.Commit()
+}
+
 type AvailableItem struct {
-	ID            int64     `json:"id"`
-	KnownItemsID  int64     `json:"knownitems_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	ExpirationAt  time.Time `json:"expiration_at,omitempty"`
-	ContainerSize int32     `json:"container_size"`
-	Version       int32     `json:"version"`
+	ID            int64      `json:"id"`
+	HouseholdID   int64      `json:"household_id"`
+	KnownItemsID  int64      `json:"knownitems_id"`
+	LocationID    *int64     `json:"location_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpirationAt  time.Time  `json:"expiration_at,omitempty"`
+	OpenedAt      *time.Time `json:"opened_at,omitempty"`
+	FrozenAt      *time.Time `json:"frozen_at,omitempty"`
+	ContainerSize int32      `json:"container_size"`
+	Remaining     int32      `json:"remaining"`
+	Version       int32      `json:"version"`
+
+	LongName        string `json:"long_name,omitempty"`
+	MeasurementName string `json:"measurement,omitempty"`
+	LocationName    string `json:"location,omitempty"`
+	DaysRemaining   *int32 `json:"days_remaining,omitempty"`
 }
 
 func ValidateAvailableItem(v *validator.Validator, availableitem *AvailableItem) {
@@ -196,4 +432,7 @@ func ValidateAvailableItem(v *validator.Validator, availableitem *AvailableItem)
 
 	v.Check(availableitem.ContainerSize >= 0, "container_size", "must be at least 0")
 	v.Check(availableitem.ContainerSize <= 100000, "container_size", "must not be more than 100000 units")
+
+	v.Check(availableitem.Remaining >= 0, "remaining", "must be at least 0")
+	v.Check(availableitem.Remaining <= availableitem.ContainerSize, "remaining", "must not be more than container_size")
 }
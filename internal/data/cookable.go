@@ -0,0 +1,213 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"householdingindex.homecatalogue.net/internal/units"
+)
+
+// CookableRecipe is a recipe with how many of its ingredients the unexpired stock
+// of their linked known items covers, and what is still missing.
+type CookableRecipe struct {
+	Recipe       *Recipe             `json:"recipe"`
+	Ingredients  int                 `json:"ingredients"`
+	Covered      int                 `json:"covered"`
+	Expiring     int                 `json:"expiring"`
+	ExpiresFirst *time.Time          `json:"expires_first,omitempty"`
+	Missing      []MissingIngredient `json:"missing"`
+}
+
+// MissingIngredient is the amount of an ingredient that is not in stock, in the
+// measurement the recipe uses.
+type MissingIngredient struct {
+	IngredientID int64   `json:"ingredient_id"`
+	Name         string  `json:"name"`
+	Amount       float64 `json:"amount"`
+	Measurement  int64   `json:"measurement"`
+}
+
+// GetCookable ranks the recipes by how few of their ingredients are missing from
+// stock, then by how many covered ingredients use stock expiring before
+// expiringBefore, then by how many are covered in all and then by how soon the
+// stock they would use expires. Stock is compared in the base unit of each measurement's
+// dimension, going between mass and volume with the ingredient's density when
+// it is known, and measurements without a known unit only match themselves.
+// A known item linked to several ingredients of a recipe is only counted once:
+// like the shopping list generator, the ingredients take what they need from it
+// in name order and later ones only get what is left. Each ingredient claims its
+// full need from every known item it is linked to, so an ingredient covered by
+// its first known item still holds back stock of its others.
+// A negative maxMissing returns every recipe.
+func (rm RecipeModel) GetCookable(householdID int64, tags []string, maxMissing int, now time.Time, expiringBefore time.Time, filters Filters) ([]*CookableRecipe, Metadata, error) {
+	query := `
+		WITH unit_names (name, dimension, factor) AS (
+			SELECT * FROM unnest($2::text[], $3::text[], $4::double precision[])
+		),
+		measurement_units AS (
+			SELECT measurements.id, COALESCE(unit_names.dimension, 'measurement:' || measurements.id) AS dimension, COALESCE(unit_names.factor, 1) AS factor
+			FROM measurements
+			LEFT JOIN unit_names ON unit_names.name = lower(trim(measurements.name))
+		),
+		stock AS (
+			SELECT knownitems.id AS knownitems_id, measurement_units.dimension,
+			SUM(availableitems.remaining * measurement_units.factor) AS amount, MIN(availableitems.expiration_at) AS expires_at
+			FROM knownitems
+			INNER JOIN availableitems ON availableitems.knownitems_id = knownitems.id
+			INNER JOIN measurement_units ON measurement_units.id = knownitems.measurement
+			WHERE knownitems.household_id = $1
+			AND availableitems.household_id = $1
+			AND availableitems.expiration_at > $5
+			AND availableitems.remaining > 0
+			GROUP BY knownitems.id, measurement_units.dimension
+		),
+		demand AS (
+			SELECT recipe_ingredients.recipe_id, recipe_ingredients.ingredient_id, ingredients.name, ingredients.density, recipe_ingredients.amount, recipe_ingredients.measurement,
+			measurement_units.dimension, measurement_units.factor
+			FROM recipe_ingredients
+			INNER JOIN recipies ON recipies.id = recipe_ingredients.recipe_id
+			INNER JOIN ingredients ON ingredients.id = recipe_ingredients.ingredient_id
+			INNER JOIN measurement_units ON measurement_units.id = recipe_ingredients.measurement
+			WHERE recipies.household_id = $1
+			AND (recipies.tags @> $6 OR $6 = '{}')
+		),
+		claims AS (
+			SELECT demand.recipe_id, demand.ingredient_id, demand.name, stock.knownitems_id, stock.dimension AS stock_dimension, stock.amount AS available, stock.expires_at,
+			CASE
+				WHEN stock.dimension = demand.dimension THEN demand.amount * demand.factor
+				WHEN stock.dimension = 'mass' THEN demand.amount * demand.factor * demand.density
+				ELSE demand.amount * demand.factor / demand.density
+			END AS needed
+			FROM demand
+			INNER JOIN ingredients_knownitems ON ingredients_knownitems.ingredient_id = demand.ingredient_id
+			INNER JOIN stock ON stock.knownitems_id = ingredients_knownitems.knownitems_id
+			AND (stock.dimension = demand.dimension
+				OR (demand.density > 0 AND stock.dimension IN ('mass', 'volume') AND demand.dimension IN ('mass', 'volume')))
+		),
+		shares AS (
+			SELECT recipe_id, ingredient_id, stock_dimension, expires_at,
+			LEAST(needed, GREATEST(available - COALESCE(SUM(needed) OVER (
+				PARTITION BY recipe_id, knownitems_id
+				ORDER BY name, ingredient_id
+				ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+			), 0), 0)) AS share
+			FROM claims
+		),
+		coverage AS (
+			SELECT demand.recipe_id, demand.ingredient_id, demand.name, demand.amount, demand.measurement,
+			round((COALESCE(SUM(
+				CASE
+					WHEN shares.stock_dimension = demand.dimension THEN shares.share
+					WHEN shares.stock_dimension = 'mass' THEN shares.share / demand.density
+					ELSE shares.share * demand.density
+				END
+			), 0) / demand.factor)::numeric, 3)::double precision AS in_stock,
+			MIN(shares.expires_at) FILTER (WHERE shares.share > 0) AS expires_at
+			FROM demand
+			LEFT JOIN shares ON shares.recipe_id = demand.recipe_id AND shares.ingredient_id = demand.ingredient_id
+			GROUP BY demand.recipe_id, demand.ingredient_id, demand.name, demand.amount, demand.measurement, demand.factor
+		),
+		ranked AS (
+			SELECT recipe_id,
+			count(*) AS ingredient_count,
+			count(*) FILTER (WHERE in_stock >= amount) AS covered_count,
+			count(*) FILTER (WHERE in_stock >= amount AND expires_at <= $10) AS expiring_count,
+			MIN(expires_at) AS expires_first,
+			array_agg(ingredient_id ORDER BY ingredient_id) FILTER (WHERE in_stock < amount) AS missing_ids,
+			array_agg(name ORDER BY ingredient_id) FILTER (WHERE in_stock < amount) AS missing_names,
+			array_agg(round((amount - in_stock)::numeric, 3)::double precision ORDER BY ingredient_id) FILTER (WHERE in_stock < amount) AS missing_amounts,
+			array_agg(measurement ORDER BY ingredient_id) FILTER (WHERE in_stock < amount) AS missing_measurements
+			FROM coverage
+			GROUP BY recipe_id
+		)
+		SELECT count(*) OVER(), recipies.id, recipies.household_id, recipies.created_at, recipies.name, recipies.description, recipies.cooking_steps,
+		recipies.cook_time_minutes, recipies.portions, recipies.tags, recipies.version,
+		ranked.ingredient_count, ranked.covered_count, ranked.expiring_count, ranked.expires_first, ranked.missing_ids, ranked.missing_names, ranked.missing_amounts, ranked.missing_measurements
+		FROM ranked
+		INNER JOIN recipies ON recipies.id = ranked.recipe_id
+		WHERE (ranked.ingredient_count - ranked.covered_count <= $7 OR $7 < 0)
+		ORDER BY ranked.ingredient_count - ranked.covered_count ASC, ranked.expiring_count DESC, ranked.covered_count DESC, ranked.expires_first ASC NULLS LAST, recipies.id ASC
+		LIMIT $8 OFFSET $9`
+
+	names := []string{}
+	dimensions := []string{}
+	factors := []float64{}
+
+	for name, unit := range units.Names() {
+		names = append(names, name)
+		dimensions = append(dimensions, string(unit.Dimension))
+		factors = append(factors, unit.Factor)
+	}
+
+	args := []interface{}{householdID, pq.Array(names), pq.Array(dimensions), pq.Array(factors), now, pq.Array(tags), maxMissing, filters.limit(), filters.offset(), expiringBefore}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := rm.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	cookable := []*CookableRecipe{}
+
+	for rows.Next() {
+		var recipe Recipe
+		var cookableRecipe CookableRecipe
+		var missingIDs, missingMeasurements []int64
+		var missingNames []string
+		var missingAmounts []float64
+
+		err := rows.Scan(
+			&totalRecords,
+			&recipe.ID,
+			&recipe.HouseholdID,
+			&recipe.CreatedAt,
+			&recipe.Name,
+			&recipe.Description,
+			pq.Array(&recipe.CookingSteps),
+			&recipe.CookTimeMinutes,
+			&recipe.Portions,
+			pq.Array(&recipe.Tags),
+			&recipe.Version,
+			&cookableRecipe.Ingredients,
+			&cookableRecipe.Covered,
+			&cookableRecipe.Expiring,
+			&cookableRecipe.ExpiresFirst,
+			pq.Array(&missingIDs),
+			pq.Array(&missingNames),
+			pq.Array(&missingAmounts),
+			pq.Array(&missingMeasurements),
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		cookableRecipe.Recipe = &recipe
+		cookableRecipe.Missing = []MissingIngredient{}
+
+		for i := range missingIDs {
+			cookableRecipe.Missing = append(cookableRecipe.Missing, MissingIngredient{
+				IngredientID: missingIDs[i],
+				Name:         missingNames[i],
+				Amount:       missingAmounts[i],
+				Measurement:  missingMeasurements[i],
+			})
+		}
+
+		cookable = append(cookable, &cookableRecipe)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return cookable, metadata, nil
+}
@@ -0,0 +1,224 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+type ExpiryAlertSettings struct {
+	UserID       int64      `json:"-"`
+	Enabled      bool       `json:"enabled"`
+	LeadDays     int32      `json:"lead_days"`
+	DeliveryHour int32      `json:"delivery_hour"`
+	LastSentAt   *time.Time `json:"last_sent_at,omitempty"`
+	Version      int32      `json:"version"`
+}
+
+type ExpiryAlertRecipient struct {
+	UserID      int64
+	Name        string
+	Email       string
+	HouseholdID int64
+	LeadDays    int32
+}
+
+type ExpiringItem struct {
+	ID            int64
+	KnownItemsID  int64
+	LongName      string
+	ExpirationAt  time.Time
+	ContainerSize int32
+	DaysRemaining int32
+}
+
+func ValidateExpiryAlertSettings(v *validator.Validator, settings *ExpiryAlertSettings) {
+	v.Check(settings.LeadDays >= 0, "lead_days", "must be at least 0")
+	v.Check(settings.LeadDays <= 60, "lead_days", "must not be more than 60")
+
+	v.Check(settings.DeliveryHour >= 0, "delivery_hour", "must be at least 0")
+	v.Check(settings.DeliveryHour <= 23, "delivery_hour", "must not be more than 23")
+}
+
+type ExpiryAlertModel struct {
+	DB *sql.DB
+}
+
+// GetForUser returns the user's settings, or the defaults with version 0 if they
+// have never changed them.
+func (m ExpiryAlertModel) GetForUser(userID int64) (*ExpiryAlertSettings, error) {
+	query := `
+		SELECT user_id, enabled, lead_days, delivery_hour, last_sent_at, version
+		FROM expiry_alert_settings
+		WHERE user_id = $1`
+
+	settings := ExpiryAlertSettings{
+		UserID:       userID,
+		LeadDays:     3,
+		DeliveryHour: 8,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&settings.UserID,
+		&settings.Enabled,
+		&settings.LeadDays,
+		&settings.DeliveryHour,
+		&settings.LastSentAt,
+		&settings.Version,
+	)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (m ExpiryAlertModel) Update(settings *ExpiryAlertSettings) error {
+	query := `
+		INSERT INTO expiry_alert_settings (user_id, enabled, lead_days, delivery_hour)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, lead_days = EXCLUDED.lead_days, delivery_hour = EXCLUDED.delivery_hour, version = expiry_alert_settings.version + 1
+		WHERE expiry_alert_settings.version = $5
+		RETURNING version`
+
+	args := []interface{}{settings.UserID, settings.Enabled, settings.LeadDays, settings.DeliveryHour, settings.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&settings.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetDue returns the activated users with alerts enabled whose delivery hour
+// (in UTC) has passed since their last digest was sent.
+func (m ExpiryAlertModel) GetDue(now time.Time) ([]*ExpiryAlertRecipient, error) {
+	query := `
+		SELECT users.id, users.name, users.email, households_users.household_id, expiry_alert_settings.lead_days
+		FROM expiry_alert_settings
+		INNER JOIN users ON users.id = expiry_alert_settings.user_id
+		INNER JOIN households_users ON households_users.user_id = users.id
+		WHERE expiry_alert_settings.enabled
+		AND users.activated
+		AND users.deletion_scheduled_at IS NULL
+		AND COALESCE(expiry_alert_settings.last_sent_at, '-infinity') < date_trunc('hour', $1::timestamptz) - make_interval(hours => ($2 - expiry_alert_settings.delivery_hour + 24) % 24)
+		ORDER BY users.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, now, now.UTC().Hour())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	recipients := []*ExpiryAlertRecipient{}
+
+	for rows.Next() {
+		var recipient ExpiryAlertRecipient
+
+		err := rows.Scan(
+			&recipient.UserID,
+			&recipient.Name,
+			&recipient.Email,
+			&recipient.HouseholdID,
+			&recipient.LeadDays,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, &recipient)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// GetExpiring returns the household's items that expire before until, including
+// those that have already expired, soonest first.
+func (m ExpiryAlertModel) GetExpiring(householdID int64, now time.Time, until time.Time) ([]*ExpiringItem, error) {
+	query := `
+		SELECT availableitems.id, availableitems.knownitems_id, knownitems.long_name, availableitems.expiration_at, availableitems.container_size,
+		availableitems.expiration_at::date - $2::date
+		FROM availableitems
+		INNER JOIN knownitems ON knownitems.id = availableitems.knownitems_id
+		WHERE availableitems.household_id = $1
+		AND availableitems.expiration_at <= $3
+		ORDER BY availableitems.expiration_at ASC, availableitems.id ASC
+		LIMIT 100`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, householdID, now, until)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []*ExpiringItem{}
+
+	for rows.Next() {
+		var item ExpiringItem
+
+		err := rows.Scan(
+			&item.ID,
+			&item.KnownItemsID,
+			&item.LongName,
+			&item.ExpirationAt,
+			&item.ContainerSize,
+			&item.DaysRemaining,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (m ExpiryAlertModel) MarkSent(userID int64, at time.Time) error {
+	query := `
+		UPDATE expiry_alert_settings
+		SET last_sent_at = $1
+		WHERE user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, at, userID)
+
+	return err
+}
@@ -0,0 +1,287 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+var householdRoleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleOwner:  3,
+}
+
+// RoleIncludes reports whether role grants at least the access of required,
+// where owners can do everything members can, and members everything viewers can.
+func RoleIncludes(role, required string) bool {
+	return householdRoleRanks[role] >= householdRoleRanks[required]
+}
+
+// ErrSoleOwner is returned when a user would leave a household that still has
+// other members without any owner.
+var ErrSoleOwner = errors.New("sole owner of a household with other members")
+
+type HouseholdModel struct {
+	DB *sql.DB
+}
+
+func (hm HouseholdModel) Insert(household *Household) error {
+	query := `
+		INSERT INTO households (name)
+		VALUES ($1)
+		RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return hm.DB.QueryRowContext(ctx, query, household.Name).Scan(&household.ID, &household.CreatedAt, &household.Version)
+}
+
+func (hm HouseholdModel) Get(id int64) (*Household, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, version
+		FROM households
+		WHERE id = $1`
+
+	var household Household
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := hm.DB.QueryRowContext(ctx, query, id).Scan(
+		&household.ID,
+		&household.CreatedAt,
+		&household.Name,
+		&household.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &household, nil
+}
+
+func (hm HouseholdModel) Update(household *Household) error {
+	query := `
+		UPDATE households
+		SET name = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+
+	args := []interface{}{household.Name, household.ID, household.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := hm.DB.QueryRowContext(ctx, query, args...).Scan(&household.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (hm HouseholdModel) AddUser(householdID int64, userID int64, role string) error {
+	query := `
+		INSERT INTO households_users (household_id, user_id, role)
+		VALUES ($1, $2, $3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := hm.DB.ExecContext(ctx, query, householdID, userID, role)
+
+	return err
+}
+
+// MoveUser makes the user a member of the given household, leaving any household
+// they previously belonged to. It returns ErrSoleOwner while the user is the only
+// owner of a household that has other members, so a household is never left
+// without an owner.
+func (hm HouseholdModel) MoveUser(householdID int64, userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := hm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var currentHouseholdID int64
+	var currentRole string
+
+	query := `
+		SELECT households.id, households_users.role
+		FROM households
+		INNER JOIN households_users ON households_users.household_id = households.id
+		WHERE households_users.user_id = $1
+		FOR UPDATE OF households`
+
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&currentHouseholdID, &currentRole)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if currentRole == RoleOwner && (currentHouseholdID != householdID || role != RoleOwner) {
+		query = `
+			SELECT count(*) FILTER (WHERE role = $3), count(*)
+			FROM households_users
+			WHERE household_id = $1 AND user_id <> $2`
+
+		var owners, members int
+
+		err = tx.QueryRowContext(ctx, query, currentHouseholdID, userID, RoleOwner).Scan(&owners, &members)
+		if err != nil {
+			return err
+		}
+
+		if members > 0 && owners == 0 {
+			return ErrSoleOwner
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM households_users WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO households_users (household_id, user_id, role)
+		VALUES ($1, $2, $3)`
+
+	_, err = tx.ExecContext(ctx, query, householdID, userID, role)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsSoleOwner reports whether the user is the only owner of a household that has
+// other members.
+func (hm HouseholdModel) IsSoleOwner(userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM households_users
+			WHERE households_users.user_id = $1
+			AND households_users.role = $2
+			AND EXISTS (SELECT 1 FROM households_users others WHERE others.household_id = households_users.household_id AND others.user_id <> $1)
+			AND NOT EXISTS (SELECT 1 FROM households_users others WHERE others.household_id = households_users.household_id AND others.user_id <> $1 AND others.role = $2)
+		)`
+
+	var soleOwner bool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := hm.DB.QueryRowContext(ctx, query, userID, RoleOwner).Scan(&soleOwner)
+
+	return soleOwner, err
+}
+
+func (hm HouseholdModel) InsertInvitation(invitation *HouseholdInvitation) error {
+	query := `
+		INSERT INTO household_invitations (token_hash, household_id, invited_by, role)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`
+
+	args := []interface{}{invitation.TokenHash, invitation.HouseholdID, invitation.InvitedBy, invitation.Role}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return hm.DB.QueryRowContext(ctx, query, args...).Scan(&invitation.CreatedAt)
+}
+
+func (hm HouseholdModel) GetInvitationForToken(tokenPlaintext string) (*HouseholdInvitation, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT household_invitations.token_hash, household_invitations.household_id, household_invitations.invited_by, household_invitations.role, household_invitations.created_at
+		FROM household_invitations
+		INNER JOIN tokens
+		ON household_invitations.token_hash = tokens.hash
+		WHERE tokens.hash = $1
+		AND tokens.scope = $2
+		AND tokens.expiry > $3`
+
+	args := []interface{}{tokenHash[:], ScopeHouseholdInvitation, time.Now()}
+
+	var invitation HouseholdInvitation
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := hm.DB.QueryRowContext(ctx, query, args...).Scan(
+		&invitation.TokenHash,
+		&invitation.HouseholdID,
+		&invitation.InvitedBy,
+		&invitation.Role,
+		&invitation.CreatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &invitation, nil
+}
+
+type Household struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"version"`
+}
+
+type HouseholdInvitation struct {
+	TokenHash   []byte    `json:"-"`
+	HouseholdID int64     `json:"household_id"`
+	InvitedBy   int64     `json:"invited_by"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func ValidateHouseholdRole(v *validator.Validator, role string) {
+	v.Check(role != "", "role", "must be provided")
+	v.Check(validator.In(role, RoleOwner, RoleMember, RoleViewer), "role", "must be one of owner, member or viewer")
+}
+
+func ValidateHousehold(v *validator.Validator, household *Household) {
+	v.Check(household.Name != "", "name", "must be provided")
+	v.Check(len(household.Name) <= 500, "name", "must not be more than 500 bytes long")
+}
@@ -0,0 +1,282 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrDuplicateIngredientKnownItem = errors.New("duplicate ingredient known item")
+
+type IngredientKnownItemModel struct {
+	DB *sql.DB
+}
+
+type IngredientKnownItem struct {
+	IngredientID int64     `json:"ingredient_id"`
+	KnownItemsID int64     `json:"knownitems_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// KnownItemSuggestion is a known item that may match an ingredient, scored by how
+// well its long name matches the ingredient name plus one for every shared tag.
+type KnownItemSuggestion struct {
+	KnownItem  *KnownItem `json:"knownitem"`
+	Score      float64    `json:"score"`
+	SharedTags []string   `json:"shared_tags"`
+}
+
+func (ik IngredientKnownItemModel) Insert(mapping *IngredientKnownItem) error {
+	query := `
+		INSERT INTO ingredients_knownitems (ingredient_id, knownitems_id)
+		VALUES ($1, $2)
+		RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ik.DB.QueryRowContext(ctx, query, mapping.IngredientID, mapping.KnownItemsID).Scan(&mapping.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "ingredients_knownitems_pkey"`:
+			return ErrDuplicateIngredientKnownItem
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAll returns the known items an ingredient is linked to.
+func (ik IngredientKnownItemModel) GetAll(householdID int64, ingredientID int64, filters Filters) ([]*KnownItem, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, household_id, knownitems.created_at, serial_number, long_name, short_name, tags, item_type, measurement, container_size, shelf_life_days, opened_shelf_life_days, frozen_shelf_life_days, par_level, par_measurement, version
+		FROM knownitems
+		INNER JOIN ingredients_knownitems ON ingredients_knownitems.knownitems_id = knownitems.id
+		WHERE knownitems.household_id = $1
+		AND ingredients_knownitems.ingredient_id = $2
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ik.DB.QueryContext(ctx, query, householdID, ingredientID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	knownitems := []*KnownItem{}
+
+	for rows.Next() {
+		var knownitem KnownItem
+
+		err := rows.Scan(
+			&totalRecords,
+			&knownitem.ID,
+			&knownitem.HouseholdID,
+			&knownitem.CreatedAt,
+			&knownitem.SerialNumber,
+			&knownitem.LongName,
+			&knownitem.ShortName,
+			pq.Array(&knownitem.Tags),
+			&knownitem.ItemType,
+			&knownitem.Measurement,
+			&knownitem.ContainerSize,
+			&knownitem.ShelfLifeDays,
+			&knownitem.OpenedShelfLifeDays,
+			&knownitem.FrozenShelfLifeDays,
+			&knownitem.ParLevel,
+			&knownitem.ParMeasurement,
+			&knownitem.Version,
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		knownitems = append(knownitems, &knownitem)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return knownitems, metadata, nil
+}
+
+// Suggest returns known items that are not yet linked to the ingredient but
+// share a word of its name or one of its tags, best matches first. The words of
+// the name are matched with OR so that a single shared word is enough.
+func (ik IngredientKnownItemModel) Suggest(ingredient *Ingredient, limit int) ([]*KnownItemSuggestion, error) {
+	query := `
+		SELECT id, household_id, created_at, serial_number, long_name, short_name, tags, item_type, measurement, container_size, shelf_life_days, opened_shelf_life_days, frozen_shelf_life_days, par_level, par_measurement, version,
+		ts_rank(to_tsvector('simple', long_name), name_query) + cardinality(shared_tags), shared_tags
+		FROM (
+			SELECT knownitems.*, name_query, ARRAY(SELECT unnest(knownitems.tags) INTERSECT SELECT unnest($3::text[]) ORDER BY 1) AS shared_tags
+			FROM knownitems, replace(plainto_tsquery('simple', $2)::text, '&', '|')::tsquery AS name_query
+			WHERE knownitems.household_id = $1
+			AND (to_tsvector('simple', knownitems.long_name) @@ name_query OR knownitems.tags && $3::text[])
+			AND NOT EXISTS (
+				SELECT 1 FROM ingredients_knownitems
+				WHERE ingredients_knownitems.ingredient_id = $4 AND ingredients_knownitems.knownitems_id = knownitems.id
+			)
+		) AS candidates
+		ORDER BY 17 DESC, id ASC
+		LIMIT $5`
+
+	args := []interface{}{ingredient.HouseholdID, ingredient.Name, pq.Array(ingredient.Tags), ingredient.ID, limit}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ik.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := []*KnownItemSuggestion{}
+
+	for rows.Next() {
+		var knownitem KnownItem
+		var suggestion KnownItemSuggestion
+
+		err := rows.Scan(
+			&knownitem.ID,
+			&knownitem.HouseholdID,
+			&knownitem.CreatedAt,
+			&knownitem.SerialNumber,
+			&knownitem.LongName,
+			&knownitem.ShortName,
+			pq.Array(&knownitem.Tags),
+			&knownitem.ItemType,
+			&knownitem.Measurement,
+			&knownitem.ContainerSize,
+			&knownitem.ShelfLifeDays,
+			&knownitem.OpenedShelfLifeDays,
+			&knownitem.FrozenShelfLifeDays,
+			&knownitem.ParLevel,
+			&knownitem.ParMeasurement,
+			&knownitem.Version,
+			&suggestion.Score,
+			pq.Array(&suggestion.SharedTags),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		suggestion.KnownItem = &knownitem
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func (ik IngredientKnownItemModel) Delete(ingredientID int64, knownitemsID int64) error {
+	if ingredientID < 1 || knownitemsID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM ingredients_knownitems
+		WHERE ingredient_id = $1 AND knownitems_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := ik.DB.ExecContext(ctx, query, ingredientID, knownitemsID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// MappedStock is the unexpired stock of a known item that an ingredient is mapped
+// to, in the known item's own measurement.
+type MappedStock struct {
+	IngredientID  int64
+	KnownItemsID  int64
+	LongName      string
+	MeasurementID int64
+	Measurement   string
+	Stock         int64
+}
+
+// GetStock returns the stock of every known item mapped to one of the
+// ingredients, with the known items of each ingredient in id order.
+func (ik IngredientKnownItemModel) GetStock(householdID int64, ingredientIDs []int64, now time.Time) ([]*MappedStock, error) {
+	query := `
+		SELECT ingredients_knownitems.ingredient_id, knownitems.id, knownitems.long_name, measurements.id, measurements.name,
+		COALESCE(SUM(availableitems.remaining) FILTER (WHERE availableitems.expiration_at > $3), 0)
+		FROM ingredients_knownitems
+		INNER JOIN knownitems ON knownitems.id = ingredients_knownitems.knownitems_id AND knownitems.household_id = $1
+		INNER JOIN measurements ON measurements.id = knownitems.measurement
+		LEFT JOIN availableitems ON availableitems.knownitems_id = knownitems.id AND availableitems.household_id = $1
+		WHERE ingredients_knownitems.ingredient_id = ANY($2)
+		GROUP BY ingredients_knownitems.ingredient_id, knownitems.id, measurements.id
+		ORDER BY ingredients_knownitems.ingredient_id ASC, knownitems.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ik.DB.QueryContext(ctx, query, householdID, pq.Array(ingredientIDs), now)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stock := []*MappedStock{}
+
+	for rows.Next() {
+		var mapped MappedStock
+
+		err := rows.Scan(
+			&mapped.IngredientID,
+			&mapped.KnownItemsID,
+			&mapped.LongName,
+			&mapped.MeasurementID,
+			&mapped.Measurement,
+			&mapped.Stock,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		stock = append(stock, &mapped)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stock, nil
+}
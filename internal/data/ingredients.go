@@ -17,11 +17,11 @@ type IngredientModel struct {
 
 func (im IngredientModel) Insert(ingredient *Ingredient) error {
 	query := `
-		INSERT INTO ingredients (name, tags)
-		VALUES ($1, $2)
+		INSERT INTO ingredients (household_id, name, tags, density)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at, version`
 
-	args := []interface{}{ingredient.Name, pq.Array(ingredient.Tags)}
+	args := []interface{}{ingredient.HouseholdID, ingredient.Name, pq.Array(ingredient.Tags), ingredient.Density}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -29,15 +29,15 @@ func (im IngredientModel) Insert(ingredient *Ingredient) error {
 	return im.DB.QueryRowContext(ctx, query, args...).Scan(&ingredient.ID, &ingredient.CreatedAt, &ingredient.Version)
 }
 
-func (im IngredientModel) Get(id int64) (*Ingredient, error) {
+func (im IngredientModel) Get(id int64, householdID int64) (*Ingredient, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, created_at, name, tags, version
+		SELECT id, household_id, created_at, name, tags, density, version
 		FROM ingredients
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2`
 
 	var ingredient Ingredient
 
@@ -45,11 +45,13 @@ func (im IngredientModel) Get(id int64) (*Ingredient, error) {
 
 	defer cancel()
 
-	err := im.DB.QueryRowContext(ctx, query, id).Scan(
+	err := im.DB.QueryRowContext(ctx, query, id, householdID).Scan(
 		&ingredient.ID,
+		&ingredient.HouseholdID,
 		&ingredient.CreatedAt,
 		&ingredient.Name,
 		pq.Array(&ingredient.Tags),
+		&ingredient.Density,
 		&ingredient.Version,
 	)
 
@@ -65,19 +67,20 @@ func (im IngredientModel) Get(id int64) (*Ingredient, error) {
 	return &ingredient, nil
 }
 
-func (im IngredientModel) GetAll(name string, tags []string, filters Filters) ([]*Ingredient, Metadata, error) {
+func (im IngredientModel) GetAll(householdID int64, name string, tags []string, filters Filters) ([]*Ingredient, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, name, tags, version
+		SELECT count(*) OVER(), id, household_id, created_at, name, tags, density, version
 		FROM ingredients
-		WHERE (name = $1 OR $1 = '')
-		AND (tags @> $2 OR $2 = '{}')
+		WHERE household_id = $1
+		AND (name = $2 OR $2 = '')
+		AND (tags @> $3 OR $3 = '{}')
 		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{name, pq.Array(tags), filters.limit(), filters.offset()}
+	args := []interface{}{householdID, name, pq.Array(tags), filters.limit(), filters.offset()}
 
 	rows, err := im.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -95,9 +98,11 @@ func (im IngredientModel) GetAll(name string, tags []string, filters Filters) ([
 		err := rows.Scan(
 			&totalRecords,
 			&ingredient.ID,
+			&ingredient.HouseholdID,
 			&ingredient.CreatedAt,
 			&ingredient.Name,
 			pq.Array(&ingredient.Tags),
+			&ingredient.Density,
 			&ingredient.Version,
 		)
 
@@ -120,14 +125,16 @@ func (im IngredientModel) GetAll(name string, tags []string, filters Filters) ([
 func (im IngredientModel) Update(ingredient *Ingredient) error {
 	query := `
 		UPDATE ingredients
-		SET name = $1, tags = $2, version = version + 1
-		WHERE id = $3 AND version = $4
+		SET name = $1, tags = $2, density = $3, version = version + 1
+		WHERE id = $4 AND household_id = $5 AND version = $6
 		RETURNING version`
 
 	args := []interface{}{
 		ingredient.Name,
 		pq.Array(ingredient.Tags),
+		ingredient.Density,
 		ingredient.ID,
+		ingredient.HouseholdID,
 		ingredient.Version,
 	}
 
@@ -147,19 +154,19 @@ func (im IngredientModel) Update(ingredient *Ingredient) error {
 	return nil
 }
 
-func (im IngredientModel) Delete(id int64) error {
+func (im IngredientModel) Delete(id int64, householdID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
 		DELETE FROM ingredients
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := im.DB.ExecContext(ctx, query, id)
+	result, err := im.DB.ExecContext(ctx, query, id, householdID)
 	if err != nil {
 		return err
 	}
@@ -177,11 +184,13 @@ func (im IngredientModel) Delete(id int64) error {
 }
 
 type Ingredient struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Name      string    `json:"name"`
-	Tags      []string  `json:"tags"`
-	Version   int32     `json:"version"`
+	ID          int64     `json:"id"`
+	HouseholdID int64     `json:"household_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Name        string    `json:"name"`
+	Tags        []string  `json:"tags"`
+	Density     float64   `json:"density"`
+	Version     int32     `json:"version"`
 }
 
 func ValidateIngredient(v *validator.Validator, ingredient *Ingredient) {
@@ -190,4 +199,7 @@ func ValidateIngredient(v *validator.Validator, ingredient *Ingredient) {
 
 	v.Check(len(ingredient.Tags) <= 100, "tags", "must not contain more than 100 tags")
 	v.Check(validator.Unique(ingredient.Tags), "tags", "must not contain duplicate values")
+
+	v.Check(ingredient.Density >= 0, "density", "must not be negative")
+	v.Check(ingredient.Density <= 25, "density", "must not be more than 25 grams per milliliter")
 }
@@ -17,11 +17,11 @@ type KnownItemModel struct {
 
 func (ki KnownItemModel) Insert(knownitem *KnownItem) error {
 	query := `
-		INSERT INTO knownitems (serial_number, long_name, short_name, tags, item_type, measurement, container_size)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO knownitems (household_id, serial_number, long_name, short_name, tags, item_type, measurement, container_size, shelf_life_days, opened_shelf_life_days, frozen_shelf_life_days, par_level, par_measurement)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, created_at, version`
 
-	args := []interface{}{knownitem.SerialNumber, knownitem.LongName, knownitem.ShortName, pq.Array(knownitem.Tags), knownitem.ItemType, knownitem.Measurement, knownitem.ContainerSize}
+	args := []interface{}{knownitem.HouseholdID, knownitem.SerialNumber, knownitem.LongName, knownitem.ShortName, pq.Array(knownitem.Tags), knownitem.ItemType, knownitem.Measurement, knownitem.ContainerSize, knownitem.ShelfLifeDays, knownitem.OpenedShelfLifeDays, knownitem.FrozenShelfLifeDays, knownitem.ParLevel, knownitem.ParMeasurement}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -29,15 +29,15 @@ func (ki KnownItemModel) Insert(knownitem *KnownItem) error {
 	return ki.DB.QueryRowContext(ctx, query, args...).Scan(&knownitem.ID, &knownitem.CreatedAt, &knownitem.Version)
 }
 
-func (ki KnownItemModel) Get(id int64) (*KnownItem, error) {
+func (ki KnownItemModel) Get(id int64, householdID int64) (*KnownItem, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, created_at, serial_number, long_name, short_name, tags, item_type, measurement, container_size, version
+		SELECT id, household_id, created_at, serial_number, long_name, short_name, tags, item_type, measurement, container_size, shelf_life_days, opened_shelf_life_days, frozen_shelf_life_days, par_level, par_measurement, version
 		FROM knownitems
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2`
 
 	var knownitem KnownItem
 
@@ -45,8 +45,9 @@ func (ki KnownItemModel) Get(id int64) (*KnownItem, error) {
 
 	defer cancel()
 
-	err := ki.DB.QueryRowContext(ctx, query, id).Scan(
+	err := ki.DB.QueryRowContext(ctx, query, id, householdID).Scan(
 		&knownitem.ID,
+		&knownitem.HouseholdID,
 		&knownitem.CreatedAt,
 		&knownitem.SerialNumber,
 		&knownitem.LongName,
@@ -55,6 +56,11 @@ func (ki KnownItemModel) Get(id int64) (*KnownItem, error) {
 		&knownitem.ItemType,
 		&knownitem.Measurement,
 		&knownitem.ContainerSize,
+		&knownitem.ShelfLifeDays,
+		&knownitem.OpenedShelfLifeDays,
+		&knownitem.FrozenShelfLifeDays,
+		&knownitem.ParLevel,
+		&knownitem.ParMeasurement,
 		&knownitem.Version,
 	)
 
@@ -70,24 +76,25 @@ func (ki KnownItemModel) Get(id int64) (*KnownItem, error) {
 	return &knownitem, nil
 }
 
-func (ki KnownItemModel) GetAll(serialnumber int, longname string, shortname string, tags []string, itemtype int, measurement int, containersize int, filters Filters) ([]*KnownItem, Metadata, error) {
+func (ki KnownItemModel) GetAll(householdID int64, serialnumber int, longname string, shortname string, tags []string, itemtype int, measurement int, containersize int, filters Filters) ([]*KnownItem, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, serial_number, long_name, short_name, tags, item_type, measurement, container_size, version
+		SELECT count(*) OVER(), id, household_id, created_at, serial_number, long_name, short_name, tags, item_type, measurement, container_size, shelf_life_days, opened_shelf_life_days, frozen_shelf_life_days, par_level, par_measurement, version
 		FROM knownitems
-		WHERE (serial_number = $1 OR $1 = 0)
-		AND (to_tsvector('simple', long_name) @@ plainto_tsquery('simple', $2) OR $2 = '')
-		AND (STRPOS(LOWER(short_name), LOWER($3)) > 0 OR $3 = '')
-		AND (tags @> $4 OR $4 = '{}')
-		AND (item_type = $5 OR $5 = 0)
-		AND (measurement = $6 OR $6 = 0)
-		AND (container_size = $7 OR $7 = 0)
+		WHERE household_id = $1
+		AND (serial_number = $2 OR $2 = 0)
+		AND (to_tsvector('simple', long_name) @@ plainto_tsquery('simple', $3) OR $3 = '')
+		AND (STRPOS(LOWER(short_name), LOWER($4)) > 0 OR $4 = '')
+		AND (tags @> $5 OR $5 = '{}')
+		AND (item_type = $6 OR $6 = 0)
+		AND (measurement = $7 OR $7 = 0)
+		AND (container_size = $8 OR $8 = 0)
 		ORDER BY %s %s, id ASC
-		LIMIT $8 OFFSET $9`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $9 OFFSET $10`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{serialnumber, longname, shortname, pq.Array(tags), itemtype, measurement, containersize, filters.limit(), filters.offset()}
+	args := []interface{}{householdID, serialnumber, longname, shortname, pq.Array(tags), itemtype, measurement, containersize, filters.limit(), filters.offset()}
 
 	rows, err := ki.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -105,6 +112,7 @@ func (ki KnownItemModel) GetAll(serialnumber int, longname string, shortname str
 		err := rows.Scan(
 			&totalRecords,
 			&knownitem.ID,
+			&knownitem.HouseholdID,
 			&knownitem.CreatedAt,
 			&knownitem.SerialNumber,
 			&knownitem.LongName,
@@ -113,6 +121,11 @@ func (ki KnownItemModel) GetAll(serialnumber int, longname string, shortname str
 			&knownitem.ItemType,
 			&knownitem.Measurement,
 			&knownitem.ContainerSize,
+			&knownitem.ShelfLifeDays,
+			&knownitem.OpenedShelfLifeDays,
+			&knownitem.FrozenShelfLifeDays,
+			&knownitem.ParLevel,
+			&knownitem.ParMeasurement,
 			&knownitem.Version,
 		)
 
@@ -135,8 +148,9 @@ func (ki KnownItemModel) GetAll(serialnumber int, longname string, shortname str
 func (ki KnownItemModel) Update(knownitem *KnownItem) error {
 	query := `
 		UPDATE knownitems
-		SET serial_number = $1, long_name = $2, short_name = $3, tags = $4, item_type = $5, measurement = $6, container_size = $7, version = version + 1
-		WHERE id = $8 AND version = $9
+		SET serial_number = $1, long_name = $2, short_name = $3, tags = $4, item_type = $5, measurement = $6, container_size = $7,
+		shelf_life_days = $8, opened_shelf_life_days = $9, frozen_shelf_life_days = $10, par_level = $11, par_measurement = $12, version = version + 1
+		WHERE id = $13 AND household_id = $14 AND version = $15
 		RETURNING version`
 
 	args := []interface{}{
@@ -147,7 +161,13 @@ func (ki KnownItemModel) Update(knownitem *KnownItem) error {
 		knownitem.ItemType,
 		knownitem.Measurement,
 		knownitem.ContainerSize,
+		knownitem.ShelfLifeDays,
+		knownitem.OpenedShelfLifeDays,
+		knownitem.FrozenShelfLifeDays,
+		knownitem.ParLevel,
+		knownitem.ParMeasurement,
 		knownitem.ID,
+		knownitem.HouseholdID,
 		knownitem.Version,
 	}
 
@@ -167,19 +187,19 @@ func (ki KnownItemModel) Update(knownitem *KnownItem) error {
 	return nil
 }
 
-func (ki KnownItemModel) Delete(id int64) error {
+func (ki KnownItemModel) Delete(id int64, householdID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
 		DELETE FROM knownitems
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := ki.DB.ExecContext(ctx, query, id)
+	result, err := ki.DB.ExecContext(ctx, query, id, householdID)
 	if err != nil {
 		return err
 	}
@@ -196,17 +216,51 @@ func (ki KnownItemModel) Delete(id int64) error {
 	return nil
 }
 
+// KnownItem describes a product. The shelf life rules are in days, with 0
+// meaning the household has not set one. ParLevel is how much the household
+// wants to keep in stock, in ParMeasurement or else the item's own measurement.
 type KnownItem struct {
-	ID            int64     `json:"id"`
-	CreatedAt     time.Time `json:"created_at"`
-	SerialNumber  int64     `json:"serial_number"`
-	LongName      string    `json:"long_name"`
-	ShortName     string    `json:"short_name"`
-	Tags          []string  `json:"tags"`
-	ItemType      int64     `json:"item_type"`
-	Measurement   int64     `json:"measurement"`
-	ContainerSize int32     `json:"container_size"`
-	Version       int32     `json:"version"`
+	ID                  int64     `json:"id"`
+	HouseholdID         int64     `json:"household_id"`
+	CreatedAt           time.Time `json:"created_at"`
+	SerialNumber        int64     `json:"serial_number"`
+	LongName            string    `json:"long_name"`
+	ShortName           string    `json:"short_name"`
+	Tags                []string  `json:"tags"`
+	ItemType            int64     `json:"item_type"`
+	Measurement         int64     `json:"measurement"`
+	ContainerSize       int32     `json:"container_size"`
+	ShelfLifeDays       int32     `json:"shelf_life_days"`
+	OpenedShelfLifeDays int32     `json:"opened_shelf_life_days"`
+	FrozenShelfLifeDays int32     `json:"frozen_shelf_life_days"`
+	ParLevel            float64   `json:"par_level"`
+	ParMeasurement      *int64    `json:"par_measurement,omitempty"`
+	Version             int32     `json:"version"`
+}
+
+// shelfLife returns the time days after from, or the zero time if there is no
+// rule.
+func shelfLife(from time.Time, days int32) time.Time {
+	if days == 0 {
+		return time.Time{}
+	}
+
+	return from.AddDate(0, 0, int(days))
+}
+
+// ExpirationSealed returns when a sealed item bought at from expires.
+func (ki *KnownItem) ExpirationSealed(from time.Time) time.Time {
+	return shelfLife(from, ki.ShelfLifeDays)
+}
+
+// ExpirationOpened returns when an item opened at from expires.
+func (ki *KnownItem) ExpirationOpened(from time.Time) time.Time {
+	return shelfLife(from, ki.OpenedShelfLifeDays)
+}
+
+// ExpirationFrozen returns when an item frozen at from expires.
+func (ki *KnownItem) ExpirationFrozen(from time.Time) time.Time {
+	return shelfLife(from, ki.FrozenShelfLifeDays)
 }
 
 func ValidateKnownItem(v *validator.Validator, knownitem *KnownItem) {
@@ -232,4 +286,16 @@ func ValidateKnownItem(v *validator.Validator, knownitem *KnownItem) {
 
 	v.Check(knownitem.ContainerSize >= 0, "container_size", "must be at least 0")
 	v.Check(knownitem.ContainerSize <= 100000, "container_size", "must not be more than 100000 units")
+
+	v.Check(knownitem.ShelfLifeDays >= 0, "shelf_life_days", "must be at least 0")
+	v.Check(knownitem.ShelfLifeDays <= 36500, "shelf_life_days", "must not be more than 36500 days")
+
+	v.Check(knownitem.OpenedShelfLifeDays >= 0, "opened_shelf_life_days", "must be at least 0")
+	v.Check(knownitem.OpenedShelfLifeDays <= 36500, "opened_shelf_life_days", "must not be more than 36500 days")
+
+	v.Check(knownitem.FrozenShelfLifeDays >= 0, "frozen_shelf_life_days", "must be at least 0")
+	v.Check(knownitem.FrozenShelfLifeDays <= 36500, "frozen_shelf_life_days", "must not be more than 36500 days")
+
+	v.Check(knownitem.ParLevel >= 0, "par_level", "must be at least 0")
+	v.Check(knownitem.ParLevel <= 1000000, "par_level", "must not be more than 1000000")
 }
@@ -0,0 +1,287 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+var ErrDuplicateLocation = errors.New("duplicate location")
+
+type LocationModel struct {
+	DB *sql.DB
+}
+
+func (l LocationModel) Insert(location *Location) error {
+	query := `
+		INSERT INTO locations (household_id, parent_id, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{location.HouseholdID, location.ParentID, location.Name}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := l.DB.QueryRowContext(ctx, query, args...).Scan(&location.ID, &location.CreatedAt, &location.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "locations_household_id_parent_id_name_idx"`:
+			return ErrDuplicateLocation
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l LocationModel) Get(id int64, householdID int64) (*Location, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, household_id, parent_id, created_at, name, version
+		FROM locations
+		WHERE id = $1 AND household_id = $2`
+
+	var location Location
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := l.DB.QueryRowContext(ctx, query, id, householdID).Scan(
+		&location.ID,
+		&location.HouseholdID,
+		&location.ParentID,
+		&location.CreatedAt,
+		&location.Name,
+		&location.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &location, nil
+}
+
+// GetAll lists the household's locations. A parentID of 0 lists all of them,
+// -1 only the top level ones and anything else the direct children of that
+// location.
+func (l LocationModel) GetAll(householdID int64, name string, parentID int, filters Filters) ([]*Location, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, household_id, parent_id, created_at, name, version
+		FROM locations
+		WHERE household_id = $1
+		AND (to_tsvector('simple', name) @@ plainto_tsquery('simple', $2) OR $2 = '')
+		AND (parent_id = $3 OR $3 = 0 OR ($3 = -1 AND parent_id IS NULL))
+		ORDER BY %s %s, id ASC
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{householdID, name, parentID, filters.limit(), filters.offset()}
+
+	rows, err := l.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	locations := []*Location{}
+
+	for rows.Next() {
+		var location Location
+
+		err := rows.Scan(
+			&totalRecords,
+			&location.ID,
+			&location.HouseholdID,
+			&location.ParentID,
+			&location.CreatedAt,
+			&location.Name,
+			&location.Version,
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		locations = append(locations, &location)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return locations, metadata, nil
+}
+
+// GetDescendantIDs returns the id of the location together with the ids of every
+// location nested below it.
+func (l LocationModel) GetDescendantIDs(id int64, householdID int64) ([]int64, error) {
+	query := `
+		WITH RECURSIVE descendants AS (
+			SELECT id FROM locations WHERE id = $1 AND household_id = $2
+			UNION
+			SELECT locations.id FROM locations
+			INNER JOIN descendants ON locations.parent_id = descendants.id
+		)
+		SELECT array_agg(id) FROM descendants`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var ids []int64
+
+	err := l.DB.QueryRowContext(ctx, query, id, householdID).Scan(pq.Array(&ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (l LocationModel) Update(location *Location) error {
+	query := `
+		UPDATE locations
+		SET parent_id = $1, name = $2, version = version + 1
+		WHERE id = $3 AND household_id = $4 AND version = $5
+		RETURNING version`
+
+	args := []interface{}{
+		location.ParentID,
+		location.Name,
+		location.ID,
+		location.HouseholdID,
+		location.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := l.DB.QueryRowContext(ctx, query, args...).Scan(&location.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "locations_household_id_parent_id_name_idx"`:
+			return ErrDuplicateLocation
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the location. Locations nested directly below it and the items
+// kept in it are moved up to its parent, or to the top level if it has none, and
+// every moved item gets a move in the stock ledger. ErrDuplicateLocation is
+// returned if a nested location has the same name as one already in the parent.
+func (l LocationModel) Delete(id int64, householdID int64, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := l.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var parentID *int64
+
+	err = tx.QueryRowContext(ctx, `SELECT parent_id FROM locations WHERE id = $1 AND household_id = $2 FOR UPDATE`, id, householdID).Scan(&parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query := `
+		UPDATE locations
+		SET parent_id = $1, version = version + 1
+		WHERE parent_id = $2 AND household_id = $3`
+
+	_, err = tx.ExecContext(ctx, query, parentID, id, householdID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "locations_household_id_parent_id_name_idx"`:
+			return ErrDuplicateLocation
+		default:
+			return err
+		}
+	}
+
+	query = `
+		WITH moved AS (
+			UPDATE availableitems
+			SET location_id = $1, version = version + 1
+			WHERE location_id = $2 AND household_id = $3
+			RETURNING id, household_id, knownitems_id, remaining
+		)
+		INSERT INTO stock_movements (household_id, availableitems_id, knownitems_id, user_id, kind, quantity_change, remaining, location_id, from_location_id)
+		SELECT household_id, id, knownitems_id, $4, $5, 0, remaining, $1, $2
+		FROM moved`
+
+	var user *int64
+	if userID != 0 {
+		user = &userID
+	}
+
+	_, err = tx.ExecContext(ctx, query, parentID, id, householdID, user, MovementMove)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM locations WHERE id = $1 AND household_id = $2`, id, householdID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type Location struct {
+	ID          int64     `json:"id"`
+	HouseholdID int64     `json:"household_id"`
+	ParentID    *int64    `json:"parent_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Name        string    `json:"name"`
+	Version     int32     `json:"version"`
+}
+
+func ValidateLocation(v *validator.Validator, location *Location) {
+	v.Check(location.Name != "", "name", "must be provided")
+	v.Check(len(location.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	if location.ParentID != nil {
+		v.Check(*location.ParentID != location.ID, "parent_id", "must not be the location itself")
+	}
+}
@@ -11,31 +11,51 @@ var (
 )
 
 type Models struct {
-	Recipies          RecipeModel
-	Ingredients       IngredientModel
-	RecipeIngredients RecipeIngredientModel
-	AvailableItems    AvailableItemModel
-	KnownItems        KnownItemModel
-	ItemTypes         ItemTypeModel
-	Measurements      MeasurementModel
-	Tags              TagModel
-	Permissions       PermissionModel
-	Tokens            TokenModel
-	Users             UserModel
+	Recipies             RecipeModel
+	Ingredients          IngredientModel
+	RecipeIngredients    RecipeIngredientModel
+	IngredientKnownItems IngredientKnownItemModel
+	AvailableItems       AvailableItemModel
+	ExpiryAlerts         ExpiryAlertModel
+	StockMovements       StockMovementModel
+	Locations            LocationModel
+	ShoppingLists        ShoppingListModel
+	ShoppingListItems    ShoppingListItemModel
+	KnownItems           KnownItemModel
+	ItemTypes            ItemTypeModel
+	Measurements         MeasurementModel
+	Tags                 TagModel
+	Permissions          PermissionModel
+	Roles                RoleModel
+	Tokens               TokenModel
+	APIKeys              APIKeyModel
+	TOTP                 TOTPModel
+	Users                UserModel
+	Households           HouseholdModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Recipies:          RecipeModel{DB: db},
-		Ingredients:       IngredientModel{DB: db},
-		RecipeIngredients: RecipeIngredientModel{DB: db},
-		AvailableItems:    AvailableItemModel{DB: db},
-		KnownItems:        KnownItemModel{DB: db},
-		ItemTypes:         ItemTypeModel{DB: db},
-		Measurements:      MeasurementModel{DB: db},
-		Tags:              TagModel{DB: db},
-		Permissions:       PermissionModel{DB: db},
-		Tokens:            TokenModel{DB: db},
-		Users:             UserModel{DB: db},
+		Recipies:             RecipeModel{DB: db},
+		Ingredients:          IngredientModel{DB: db},
+		RecipeIngredients:    RecipeIngredientModel{DB: db},
+		IngredientKnownItems: IngredientKnownItemModel{DB: db},
+		AvailableItems:       AvailableItemModel{DB: db},
+		ExpiryAlerts:         ExpiryAlertModel{DB: db},
+		StockMovements:       StockMovementModel{DB: db},
+		Locations:            LocationModel{DB: db},
+		ShoppingLists:        ShoppingListModel{DB: db},
+		ShoppingListItems:    ShoppingListItemModel{DB: db},
+		KnownItems:           KnownItemModel{DB: db},
+		ItemTypes:            ItemTypeModel{DB: db},
+		Measurements:         MeasurementModel{DB: db},
+		Tags:                 TagModel{DB: db},
+		Permissions:          PermissionModel{DB: db},
+		Roles:                RoleModel{DB: db},
+		Tokens:               TokenModel{DB: db},
+		APIKeys:              APIKeyModel{DB: db},
+		TOTP:                 TOTPModel{DB: db},
+		Users:                UserModel{DB: db},
+		Households:           HouseholdModel{DB: db},
 	}
 }
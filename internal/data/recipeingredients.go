@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"householdingindex.homecatalogue.net/internal/validator"
 )
 
@@ -29,7 +29,7 @@ func (rm RecipeIngredientModel) Insert(recipeingredient *RecipeIngredient) error
 	return rm.DB.QueryRowContext(ctx, query, args...).Scan(&recipeingredient.RecipeID, &recipeingredient.IngredientID, &recipeingredient.CreatedAt, &recipeingredient.Version)
 }
 
-func (rm RecipeIngredientModel) Get(recipeid int64, ingredientid int64) (*RecipeIngredient, error) {
+func (rm RecipeIngredientModel) Get(recipeid int64, ingredientid int64, householdID int64) (*RecipeIngredient, error) {
 	if recipeid < 1 {
 		return nil, ErrRecordNotFound
 	}
@@ -41,11 +41,12 @@ func (rm RecipeIngredientModel) Get(recipeid int64, ingredientid int64) (*Recipe
 	query := `
 		SELECT recipe_id, ingredient_id, created_at, amount, measurement, version
 		FROM recipe_ingredients
-		WHERE recipe_id = $1 AND ingredient_id = $2`
+		WHERE recipe_id = $1 AND ingredient_id = $2
+		AND recipe_id IN (SELECT id FROM recipies WHERE household_id = $3)`
 
 	var recipeingredient RecipeIngredient
 
-	args := []interface{}{recipeid, ingredientid}
+	args := []interface{}{recipeid, ingredientid, householdID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -71,19 +72,20 @@ func (rm RecipeIngredientModel) Get(recipeid int64, ingredientid int64) (*Recipe
 	return &recipeingredient, nil
 }
 
-func (rm RecipeIngredientModel) GetAll(amount int, measurement int, filters Filters) ([]*RecipeIngredient, Metadata, error) {
+func (rm RecipeIngredientModel) GetAll(householdID int64, amount int, measurement int, filters Filters) ([]*RecipeIngredient, Metadata, error) {
 	query := fmt.Sprintf(`
 		SELECT count(*) OVER(), recipe_id, ingredient_id, created_at, amount, measurement, version
 		FROM recipe_ingredients
-		WHERE (amount = $1 OR $1 = 0)
-		AND (measurement = $2 OR $2 = 0)
+		WHERE recipe_id IN (SELECT id FROM recipies WHERE household_id = $1)
+		AND (amount = $2 OR $2 = 0)
+		AND (measurement = $3 OR $3 = 0)
 		ORDER BY %s %s, recipe_id ASC, ingredient_id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{amount, measurement, filters.limit(), filters.offset()}
+	args := []interface{}{householdID, amount, measurement, filters.limit(), filters.offset()}
 
 	rows, err := rm.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -124,11 +126,12 @@ func (rm RecipeIngredientModel) GetAll(amount int, measurement int, filters Filt
 	return recipeingredients, metadata, nil
 }
 
-func (mm RecipeIngredientModel) Update(recipeingredient *RecipeIngredient, oldrecipeid *int64, oldingredientid *int64) error {
+func (mm RecipeIngredientModel) Update(recipeingredient *RecipeIngredient, oldrecipeid *int64, oldingredientid *int64, householdID int64) error {
 	query := `
 		UPDATE recipe_ingredients
 		SET recipe_id = $1, ingredient_id = $2, amount = $3, measurement = $4, version = version + 1
 		WHERE recipe_id = $5 AND ingredient_id = $6 AND version = $7
+		AND recipe_id IN (SELECT id FROM recipies WHERE household_id = $8)
 		RETURNING version`
 
 	args := []interface{}{
@@ -139,6 +142,7 @@ func (mm RecipeIngredientModel) Update(recipeingredient *RecipeIngredient, oldre
 		oldrecipeid,
 		oldingredientid,
 		recipeingredient.Version,
+		householdID,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -157,7 +161,7 @@ func (mm RecipeIngredientModel) Update(recipeingredient *RecipeIngredient, oldre
 	return nil
 }
 
-func (rm RecipeIngredientModel) Delete(recipeid int64, ingredientid int64) error {
+func (rm RecipeIngredientModel) Delete(recipeid int64, ingredientid int64, householdID int64) error {
 	if recipeid < 1 {
 		return ErrRecordNotFound
 	}
@@ -168,9 +172,10 @@ func (rm RecipeIngredientModel) Delete(recipeid int64, ingredientid int64) error
 
 	query := `
 		DELETE FROM recipe_ingredients
-		WHERE recipe_id = $1 AND ingredient_id = $2`
+		WHERE recipe_id = $1 AND ingredient_id = $2
+		AND recipe_id IN (SELECT id FROM recipies WHERE household_id = $3)`
 
-	args := []interface{}{recipeid, ingredientid}
+	args := []interface{}{recipeid, ingredientid, householdID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -192,6 +197,68 @@ func (rm RecipeIngredientModel) Delete(recipeid int64, ingredientid int64) error
 	return nil
 }
 
+// RequiredIngredient is the total amount of an ingredient needed by a set of
+// recipes, in one of the measurements the recipes use for it.
+type RequiredIngredient struct {
+	IngredientID  int64
+	Name          string
+	Amount        float64
+	MeasurementID int64
+	Measurement   string
+	Density       float64
+}
+
+// GetRequired sums the ingredients of the recipes, each multiplied by the matching
+// entry in multipliers.
+func (rm RecipeIngredientModel) GetRequired(householdID int64, recipeIDs []int64, multipliers []float64) ([]*RequiredIngredient, error) {
+	query := `
+		SELECT ingredients.id, ingredients.name, SUM(recipe_ingredients.amount * plan.multiplier), measurements.id, measurements.name, ingredients.density
+		FROM unnest($2::bigint[], $3::double precision[]) AS plan(recipe_id, multiplier)
+		INNER JOIN recipies ON recipies.id = plan.recipe_id AND recipies.household_id = $1
+		INNER JOIN recipe_ingredients ON recipe_ingredients.recipe_id = recipies.id
+		INNER JOIN ingredients ON ingredients.id = recipe_ingredients.ingredient_id
+		INNER JOIN measurements ON measurements.id = recipe_ingredients.measurement
+		GROUP BY ingredients.id, measurements.id
+		ORDER BY ingredients.name ASC, ingredients.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := rm.DB.QueryContext(ctx, query, householdID, pq.Array(recipeIDs), pq.Array(multipliers))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	required := []*RequiredIngredient{}
+
+	for rows.Next() {
+		var ingredient RequiredIngredient
+
+		err := rows.Scan(
+			&ingredient.IngredientID,
+			&ingredient.Name,
+			&ingredient.Amount,
+			&ingredient.MeasurementID,
+			&ingredient.Measurement,
+			&ingredient.Density,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		required = append(required, &ingredient)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return required, nil
+}
+
 type RecipeIngredient struct {
 	RecipeID     int64     `json:"recipe_id"`
 	IngredientID int64     `json:"ingredient_id"`
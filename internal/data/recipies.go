@@ -17,11 +17,11 @@ type RecipeModel struct {
 
 func (rm RecipeModel) Insert(recipe *Recipe) error {
 	query := `
-		INSERT INTO recipies (name, description, cooking_steps, cook_time_minutes, portions, tags)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO recipies (household_id, name, description, cooking_steps, cook_time_minutes, portions, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, version`
 
-	args := []interface{}{recipe.Name, recipe.Description, pq.Array(recipe.CookingSteps), recipe.CookTimeMinutes, recipe.Portions, pq.Array(recipe.Tags)}
+	args := []interface{}{recipe.HouseholdID, recipe.Name, recipe.Description, pq.Array(recipe.CookingSteps), recipe.CookTimeMinutes, recipe.Portions, pq.Array(recipe.Tags)}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -29,15 +29,15 @@ func (rm RecipeModel) Insert(recipe *Recipe) error {
 	return rm.DB.QueryRowContext(ctx, query, args...).Scan(&recipe.ID, &recipe.CreatedAt, &recipe.Version)
 }
 
-func (rm RecipeModel) Get(id int64) (*Recipe, error) {
+func (rm RecipeModel) Get(id int64, householdID int64) (*Recipe, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, created_at, name, description, cooking_steps, cook_time_minutes, portions, tags, version
+		SELECT id, household_id, created_at, name, description, cooking_steps, cook_time_minutes, portions, tags, version
 		FROM recipies
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2`
 
 	var recipe Recipe
 
@@ -45,8 +45,9 @@ func (rm RecipeModel) Get(id int64) (*Recipe, error) {
 
 	defer cancel()
 
-	err := rm.DB.QueryRowContext(ctx, query, id).Scan(
+	err := rm.DB.QueryRowContext(ctx, query, id, householdID).Scan(
 		&recipe.ID,
+		&recipe.HouseholdID,
 		&recipe.CreatedAt,
 		&recipe.Name,
 		&recipe.Description,
@@ -69,23 +70,24 @@ func (rm RecipeModel) Get(id int64) (*Recipe, error) {
 	return &recipe, nil
 }
 
-func (rm RecipeModel) GetAll(name string, description string, cookingsteps []string, cooktimeminutes int, portions int, tags []string, filters Filters) ([]*Recipe, Metadata, error) {
+func (rm RecipeModel) GetAll(householdID int64, name string, description string, cookingsteps []string, cooktimeminutes int, portions int, tags []string, filters Filters) ([]*Recipe, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, name, description, cooking_steps, cook_time_minutes, portions, tags, version
+		SELECT count(*) OVER(), id, household_id, created_at, name, description, cooking_steps, cook_time_minutes, portions, tags, version
 		FROM recipies
-		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (description = $2 OR $2 = '')
-		AND (cooking_steps @> $3 OR $3 = '{}')
-		AND (cook_time_minutes = $4 OR $4 = 0)
-		AND (portions = $5 OR $5 = 0)
-		AND (tags @> $6 OR $6 = '{}')
+		WHERE household_id = $1
+		AND (to_tsvector('simple', name) @@ plainto_tsquery('simple', $2) OR $2 = '')
+		AND (description = $3 OR $3 = '')
+		AND (cooking_steps @> $4 OR $4 = '{}')
+		AND (cook_time_minutes = $5 OR $5 = 0)
+		AND (portions = $6 OR $6 = 0)
+		AND (tags @> $7 OR $7 = '{}')
 		ORDER BY %s %s, id ASC
-		LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $8 OFFSET $9`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{name, description, pq.Array(cookingsteps), cooktimeminutes, portions, pq.Array(tags), filters.limit(), filters.offset()}
+	args := []interface{}{householdID, name, description, pq.Array(cookingsteps), cooktimeminutes, portions, pq.Array(tags), filters.limit(), filters.offset()}
 
 	rows, err := rm.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -103,6 +105,7 @@ func (rm RecipeModel) GetAll(name string, description string, cookingsteps []str
 		err := rows.Scan(
 			&totalRecords,
 			&recipe.ID,
+			&recipe.HouseholdID,
 			&recipe.CreatedAt,
 			&recipe.Name,
 			&recipe.Description,
@@ -133,7 +136,7 @@ func (rm RecipeModel) Update(recipe *Recipe) error {
 	query := `
 		UPDATE recipies
 		SET name = $1, description = $2, cooking_steps = $3, cook_time_minutes = $4, portions = $5, tags = $6, version = version + 1
-		WHERE id = $7 AND version = $8
+		WHERE id = $7 AND household_id = $8 AND version = $9
 		RETURNING version`
 
 	args := []interface{}{
@@ -144,6 +147,7 @@ func (rm RecipeModel) Update(recipe *Recipe) error {
 		recipe.Portions,
 		pq.Array(recipe.Tags),
 		recipe.ID,
+		recipe.HouseholdID,
 		recipe.Version,
 	}
 
@@ -163,19 +167,19 @@ func (rm RecipeModel) Update(recipe *Recipe) error {
 	return nil
 }
 
-func (rm RecipeModel) Delete(id int64) error {
+func (rm RecipeModel) Delete(id int64, householdID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
 		DELETE FROM recipies
-		WHERE id = $1`
+		WHERE id = $1 AND household_id = $2`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := rm.DB.ExecContext(ctx, query, id)
+	result, err := rm.DB.ExecContext(ctx, query, id, householdID)
 	if err != nil {
 		return err
 	}
@@ -194,6 +198,7 @@ func (rm RecipeModel) Delete(id int64) error {
 
 type Recipe struct {
 	ID              int64     `json:"id"`
+	HouseholdID     int64     `json:"household_id"`
 	CreatedAt       time.Time `json:"created_at"`
 	Name            string    `json:"name"`
 	Description     string    `json:"description"`
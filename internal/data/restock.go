@@ -0,0 +1,75 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// StockLevel is how much of a known item with a par level the household has in
+// stock, counting only what remains of items that have not expired yet. Stock is
+// in the item's own measurement and ParLevel in ParMeasurement.
+type StockLevel struct {
+	KnownItemsID     int64
+	LongName         string
+	ParLevel         float64
+	ParMeasurementID int64
+	ParMeasurement   string
+	MeasurementID    int64
+	Measurement      string
+	Stock            int64
+}
+
+func (ki KnownItemModel) GetStockLevels(householdID int64, now time.Time) ([]*StockLevel, error) {
+	query := `
+		SELECT knownitems.id, knownitems.long_name, knownitems.par_level,
+		COALESCE(knownitems.par_measurement, knownitems.measurement), COALESCE(par_measurements.name, measurements.name),
+		knownitems.measurement, measurements.name,
+		COALESCE(SUM(availableitems.remaining) FILTER (WHERE availableitems.expiration_at > $2), 0)
+		FROM knownitems
+		INNER JOIN measurements ON measurements.id = knownitems.measurement
+		LEFT JOIN measurements par_measurements ON par_measurements.id = knownitems.par_measurement
+		LEFT JOIN availableitems ON availableitems.knownitems_id = knownitems.id AND availableitems.household_id = knownitems.household_id
+		WHERE knownitems.household_id = $1
+		AND knownitems.par_level > 0
+		GROUP BY knownitems.id, par_measurements.name, measurements.name
+		ORDER BY knownitems.long_name ASC, knownitems.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ki.DB.QueryContext(ctx, query, householdID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	levels := []*StockLevel{}
+
+	for rows.Next() {
+		var level StockLevel
+
+		err := rows.Scan(
+			&level.KnownItemsID,
+			&level.LongName,
+			&level.ParLevel,
+			&level.ParMeasurementID,
+			&level.ParMeasurement,
+			&level.MeasurementID,
+			&level.Measurement,
+			&level.Stock,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		levels = append(levels, &level)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
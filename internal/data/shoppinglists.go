@@ -0,0 +1,784 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+	"householdingindex.homecatalogue.net/internal/units"
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+var (
+	ErrUnconvertibleQuantity = errors.New("cannot be converted to the known item's measurement")
+	ErrInvalidCheckoutItem   = errors.New("does not make a valid available item")
+)
+
+type ShoppingListModel struct {
+	DB *sql.DB
+}
+
+func (sl ShoppingListModel) Insert(shoppinglist *ShoppingList) error {
+	query := `
+		INSERT INTO shoppinglists (household_id, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{shoppinglist.HouseholdID, shoppinglist.Name}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return sl.DB.QueryRowContext(ctx, query, args...).Scan(&shoppinglist.ID, &shoppinglist.CreatedAt, &shoppinglist.Version)
+}
+
+func (sl ShoppingListModel) Get(id int64, householdID int64) (*ShoppingList, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT shoppinglists.id, shoppinglists.household_id, shoppinglists.created_at, shoppinglists.name, shoppinglists.version,
+		count(shoppinglist_items.id), count(shoppinglist_items.id) FILTER (WHERE shoppinglist_items.checked)
+		FROM shoppinglists
+		LEFT JOIN shoppinglist_items ON shoppinglist_items.shoppinglist_id = shoppinglists.id
+		WHERE shoppinglists.id = $1 AND shoppinglists.household_id = $2
+		GROUP BY shoppinglists.id`
+
+	var shoppinglist ShoppingList
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := sl.DB.QueryRowContext(ctx, query, id, householdID).Scan(
+		&shoppinglist.ID,
+		&shoppinglist.HouseholdID,
+		&shoppinglist.CreatedAt,
+		&shoppinglist.Name,
+		&shoppinglist.Version,
+		&shoppinglist.ItemCount,
+		&shoppinglist.CheckedCount,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &shoppinglist, nil
+}
+
+func (sl ShoppingListModel) GetAll(householdID int64, name string, filters Filters) ([]*ShoppingList, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), shoppinglists.id, shoppinglists.household_id, shoppinglists.created_at, shoppinglists.name, shoppinglists.version,
+		count(shoppinglist_items.id), count(shoppinglist_items.id) FILTER (WHERE shoppinglist_items.checked)
+		FROM shoppinglists
+		LEFT JOIN shoppinglist_items ON shoppinglist_items.shoppinglist_id = shoppinglists.id
+		WHERE shoppinglists.household_id = $1
+		AND (to_tsvector('simple', shoppinglists.name) @@ plainto_tsquery('simple', $2) OR $2 = '')
+		GROUP BY shoppinglists.id
+		ORDER BY shoppinglists.%s %s, shoppinglists.id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{householdID, name, filters.limit(), filters.offset()}
+
+	rows, err := sl.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	shoppinglists := []*ShoppingList{}
+
+	for rows.Next() {
+		var shoppinglist ShoppingList
+
+		err := rows.Scan(
+			&totalRecords,
+			&shoppinglist.ID,
+			&shoppinglist.HouseholdID,
+			&shoppinglist.CreatedAt,
+			&shoppinglist.Name,
+			&shoppinglist.Version,
+			&shoppinglist.ItemCount,
+			&shoppinglist.CheckedCount,
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		shoppinglists = append(shoppinglists, &shoppinglist)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return shoppinglists, metadata, nil
+}
+
+func (sl ShoppingListModel) Update(shoppinglist *ShoppingList) error {
+	query := `
+		UPDATE shoppinglists
+		SET name = $1, version = version + 1
+		WHERE id = $2 AND household_id = $3 AND version = $4
+		RETURNING version`
+
+	args := []interface{}{
+		shoppinglist.Name,
+		shoppinglist.ID,
+		shoppinglist.HouseholdID,
+		shoppinglist.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := sl.DB.QueryRowContext(ctx, query, args...).Scan(&shoppinglist.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sl ShoppingListModel) Delete(id int64, householdID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM shoppinglists
+		WHERE id = $1 AND household_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := sl.DB.ExecContext(ctx, query, id, householdID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+type checkoutEntry struct {
+	id                int64
+	knownitemsID      *int64
+	quantity          float64
+	measurementID     *int64
+	measurement       string
+	itemMeasurementID int64
+	itemMeasurement   string
+	itemContainerSize int32
+	itemShelfLifeDays int32
+}
+
+// Checkout turns the checked entries of the list into available items and
+// removes them from the list, all in one transaction. Entries without a
+// measurement count packages of the known item, others are converted to the known
+// item's measurement and become a single item. Checked free text entries are
+// only removed. The items expire after the known item's shelf life, or at
+// expirationAt for known items without one. An entry that would make an empty or
+// otherwise invalid item fails the whole checkout with ErrInvalidCheckoutItem.
+func (sl ShoppingListModel) Checkout(shoppinglist *ShoppingList, userID int64, locationID *int64, expirationAt time.Time, now time.Time) ([]*AvailableItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := sl.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		SELECT shoppinglist_items.id, shoppinglist_items.knownitems_id, shoppinglist_items.quantity, shoppinglist_items.measurement, COALESCE(measurements.name, ''),
+		COALESCE(knownitems.measurement, 0), COALESCE(item_measurements.name, ''), COALESCE(knownitems.container_size, 0), COALESCE(knownitems.shelf_life_days, 0)
+		FROM shoppinglist_items
+		LEFT JOIN knownitems ON knownitems.id = shoppinglist_items.knownitems_id
+		LEFT JOIN measurements ON measurements.id = shoppinglist_items.measurement
+		LEFT JOIN measurements item_measurements ON item_measurements.id = knownitems.measurement
+		WHERE shoppinglist_items.shoppinglist_id = $1 AND shoppinglist_items.checked
+		ORDER BY shoppinglist_items.id
+		FOR UPDATE OF shoppinglist_items`
+
+	rows, err := tx.QueryContext(ctx, query, shoppinglist.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*checkoutEntry{}
+
+	for rows.Next() {
+		var entry checkoutEntry
+
+		err := rows.Scan(
+			&entry.id,
+			&entry.knownitemsID,
+			&entry.quantity,
+			&entry.measurementID,
+			&entry.measurement,
+			&entry.itemMeasurementID,
+			&entry.itemMeasurement,
+			&entry.itemContainerSize,
+			&entry.itemShelfLifeDays,
+		)
+
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	availableitems := []*AvailableItem{}
+	ids := []int64{}
+
+	for _, entry := range entries {
+		ids = append(ids, entry.id)
+
+		if entry.knownitemsID == nil {
+			continue
+		}
+
+		expiration := shelfLife(now, entry.itemShelfLifeDays)
+		if expiration.IsZero() {
+			expiration = expirationAt
+		}
+
+		containers, err := checkoutContainers(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, size := range containers {
+			availableitem := &AvailableItem{
+				HouseholdID:   shoppinglist.HouseholdID,
+				KnownItemsID:  *entry.knownitemsID,
+				LocationID:    locationID,
+				ExpirationAt:  expiration,
+				ContainerSize: size,
+				Remaining:     size,
+			}
+
+			v := validator.New()
+
+			v.Check(!availableitem.ExpirationAt.IsZero(), "expiration_at", "must be provided for known items without a shelf life")
+			v.Check(availableitem.ContainerSize > 0, "container_size", "must be greater than zero")
+
+			if ValidateAvailableItem(v, availableitem); !v.Valid() {
+				for key, message := range v.Errors {
+					return nil, fmt.Errorf("shopping list item %d %w, %s %s", entry.id, ErrInvalidCheckoutItem, key, message)
+				}
+			}
+
+			err = insertAvailableItem(ctx, tx, availableitem, userID)
+			if err != nil {
+				return nil, err
+			}
+
+			availableitems = append(availableitems, availableitem)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM shoppinglist_items WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return availableitems, tx.Commit()
+}
+
+// checkoutContainers returns the container sizes of the available items an entry
+// becomes, in the known item's measurement.
+func checkoutContainers(entry *checkoutEntry) ([]int32, error) {
+	containers := []int32{}
+
+	switch {
+	case entry.measurementID == nil:
+		for i := 0; i < int(math.Ceil(entry.quantity)); i++ {
+			containers = append(containers, entry.itemContainerSize)
+		}
+	case *entry.measurementID == entry.itemMeasurementID:
+		containers = append(containers, int32(math.Round(entry.quantity)))
+	default:
+		amount, err := convertEntryQuantity(entry)
+		if err != nil {
+			return nil, fmt.Errorf("quantity of shopping list item %d %w", entry.id, ErrUnconvertibleQuantity)
+		}
+
+		containers = append(containers, int32(math.Round(amount)))
+	}
+
+	return containers, nil
+}
+
+func convertEntryQuantity(entry *checkoutEntry) (float64, error) {
+	from, err := units.Lookup(entry.measurement)
+	if err != nil {
+		return 0, err
+	}
+
+	to, err := units.Lookup(entry.itemMeasurement)
+	if err != nil {
+		return 0, err
+	}
+
+	return units.Convert(entry.quantity, from, to)
+}
+
+// PlanShoppingList works out what is missing of the required ingredients once
+// the stock of the known items linked to them is used up, in known item order and
+// counting a known item shared by several ingredients only once. It returns an
+// entry for every missing ingredient and the ingredients stock already covers.
+// Stock is converted between mass and volume with the ingredient's density, but
+// an entry is only linked to a known item its measurement converts to without
+// one, since checkout does not know the ingredient. Other entries are free text.
+func PlanShoppingList(shoppinglistID int64, userID int64, required []*RequiredIngredient, mappedStock []*MappedStock) ([]*ShoppingListItem, []*RequiredIngredient, error) {
+	mapped := make(map[int64][]*MappedStock)
+	available := make(map[int64]float64)
+
+	for _, stock := range mappedStock {
+		mapped[stock.IngredientID] = append(mapped[stock.IngredientID], stock)
+		available[stock.KnownItemsID] = float64(stock.Stock)
+	}
+
+	items := []*ShoppingListItem{}
+	covered := []*RequiredIngredient{}
+
+	for _, ingredient := range required {
+		missing := ingredient.Amount
+
+		var knownitemsID *int64
+
+		for _, stock := range mapped[ingredient.IngredientID] {
+			if knownitemsID == nil {
+				_, err := convertMeasurement(ingredient.Amount, ingredient.MeasurementID, ingredient.Measurement, stock.MeasurementID, stock.Measurement, 0)
+				if err == nil {
+					knownitemsID = &stock.KnownItemsID
+				}
+			}
+
+			if missing <= 0 {
+				continue
+			}
+
+			inStock, err := convertMeasurement(available[stock.KnownItemsID], stock.MeasurementID, stock.Measurement, ingredient.MeasurementID, ingredient.Measurement, ingredient.Density)
+			if err != nil {
+				continue
+			}
+
+			used := math.Min(missing, inStock)
+			missing -= used
+
+			usedStock, err := convertMeasurement(used, ingredient.MeasurementID, ingredient.Measurement, stock.MeasurementID, stock.Measurement, ingredient.Density)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			available[stock.KnownItemsID] -= usedStock
+		}
+
+		missing = math.Round(missing*1000) / 1000
+
+		if missing <= 0 {
+			covered = append(covered, ingredient)
+			continue
+		}
+
+		item := &ShoppingListItem{
+			ShoppingListID: shoppinglistID,
+			KnownItemsID:   knownitemsID,
+			Quantity:       math.Min(missing, 100000),
+			Measurement:    &ingredient.MeasurementID,
+			UserID:         &userID,
+		}
+
+		if knownitemsID == nil {
+			item.Text = ingredient.Name
+		}
+
+		items = append(items, item)
+	}
+
+	return items, covered, nil
+}
+
+// convertMeasurement converts an amount between two measurements given both their
+// ids and names, skipping the conversion when they are the same measurement.
+func convertMeasurement(amount float64, fromID int64, from string, toID int64, to string, density float64) (float64, error) {
+	if fromID == toID {
+		return amount, nil
+	}
+
+	fromUnit, err := units.Lookup(from)
+	if err != nil {
+		return 0, err
+	}
+
+	toUnit, err := units.Lookup(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return units.ConvertDensity(amount, fromUnit, toUnit, density)
+}
+
+type ShoppingList struct {
+	ID           int64     `json:"id"`
+	HouseholdID  int64     `json:"household_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	ItemCount    int32     `json:"item_count"`
+	CheckedCount int32     `json:"checked_count"`
+	Version      int32     `json:"version"`
+}
+
+func ValidateShoppingList(v *validator.Validator, shoppinglist *ShoppingList) {
+	v.Check(shoppinglist.Name != "", "name", "must be provided")
+	v.Check(len(shoppinglist.Name) <= 500, "name", "must not be more than 500 bytes long")
+}
+
+type ShoppingListItemModel struct {
+	DB *sql.DB
+}
+
+func (sli ShoppingListItemModel) Insert(item *ShoppingListItem) error {
+	query := `
+		INSERT INTO shoppinglist_items (shoppinglist_id, knownitems_id, text, quantity, measurement, checked, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{item.ShoppingListID, item.KnownItemsID, item.Text, item.Quantity, item.Measurement, item.Checked, item.UserID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return sli.DB.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt, &item.Version)
+}
+
+// InsertAll adds all of the items in one transaction, so a list is never left
+// half filled.
+func (sli ShoppingListItemModel) InsertAll(items []*ShoppingListItem) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := sli.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO shoppinglist_items (shoppinglist_id, knownitems_id, text, quantity, measurement, checked, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, version`
+
+	for _, item := range items {
+		args := []interface{}{item.ShoppingListID, item.KnownItemsID, item.Text, item.Quantity, item.Measurement, item.Checked, item.UserID}
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt, &item.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (sli ShoppingListItemModel) Get(id int64, shoppinglistID int64) (*ShoppingListItem, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, shoppinglist_id, knownitems_id, text, quantity, measurement, checked, user_id, created_at, version
+		FROM shoppinglist_items
+		WHERE id = $1 AND shoppinglist_id = $2`
+
+	var item ShoppingListItem
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+
+	defer cancel()
+
+	err := sli.DB.QueryRowContext(ctx, query, id, shoppinglistID).Scan(
+		&item.ID,
+		&item.ShoppingListID,
+		&item.KnownItemsID,
+		&item.Text,
+		&item.Quantity,
+		&item.Measurement,
+		&item.Checked,
+		&item.UserID,
+		&item.CreatedAt,
+		&item.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &item, nil
+}
+
+func (sli ShoppingListItemModel) GetAll(shoppinglistID int64, knownitemsid int, checked *bool, filters Filters) ([]*ShoppingListItem, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), shoppinglist_items.id, shoppinglist_items.shoppinglist_id, shoppinglist_items.knownitems_id, shoppinglist_items.text,
+		shoppinglist_items.quantity, shoppinglist_items.measurement, shoppinglist_items.checked, shoppinglist_items.user_id, shoppinglist_items.created_at, shoppinglist_items.version,
+		COALESCE(knownitems.long_name, ''), COALESCE(measurements.name, ''), COALESCE(users.name, '')
+		FROM shoppinglist_items
+		LEFT JOIN knownitems ON knownitems.id = shoppinglist_items.knownitems_id
+		LEFT JOIN measurements ON measurements.id = shoppinglist_items.measurement
+		LEFT JOIN users ON users.id = shoppinglist_items.user_id
+		WHERE shoppinglist_items.shoppinglist_id = $1
+		AND (shoppinglist_items.knownitems_id = $2 OR $2 = 0)
+		AND ($3::boolean IS NULL OR shoppinglist_items.checked = $3)
+		ORDER BY shoppinglist_items.%s %s, shoppinglist_items.id ASC
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{shoppinglistID, knownitemsid, checked, filters.limit(), filters.offset()}
+
+	rows, err := sli.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	items := []*ShoppingListItem{}
+
+	for rows.Next() {
+		var item ShoppingListItem
+
+		err := rows.Scan(
+			&totalRecords,
+			&item.ID,
+			&item.ShoppingListID,
+			&item.KnownItemsID,
+			&item.Text,
+			&item.Quantity,
+			&item.Measurement,
+			&item.Checked,
+			&item.UserID,
+			&item.CreatedAt,
+			&item.Version,
+			&item.LongName,
+			&item.MeasurementName,
+			&item.UserName,
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return items, metadata, nil
+}
+
+func (sli ShoppingListItemModel) Update(item *ShoppingListItem) error {
+	query := `
+		UPDATE shoppinglist_items
+		SET knownitems_id = $1, text = $2, quantity = $3, measurement = $4, checked = $5, version = version + 1
+		WHERE id = $6 AND shoppinglist_id = $7 AND version = $8
+		RETURNING version`
+
+	args := []interface{}{
+		item.KnownItemsID,
+		item.Text,
+		item.Quantity,
+		item.Measurement,
+		item.Checked,
+		item.ID,
+		item.ShoppingListID,
+		item.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := sli.DB.QueryRowContext(ctx, query, args...).Scan(&item.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sli ShoppingListItemModel) Delete(id int64, shoppinglistID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM shoppinglist_items
+		WHERE id = $1 AND shoppinglist_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := sli.DB.ExecContext(ctx, query, id, shoppinglistID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ShoppingListItem is an entry on a shopping list, either a known item or free
+// text. Quantity is in Measurement, or counts packages when it is not set.
+type ShoppingListItem struct {
+	ID             int64     `json:"id"`
+	ShoppingListID int64     `json:"shoppinglist_id"`
+	KnownItemsID   *int64    `json:"knownitems_id"`
+	Text           string    `json:"text,omitempty"`
+	Quantity       float64   `json:"quantity"`
+	Measurement    *int64    `json:"measurement"`
+	Checked        bool      `json:"checked"`
+	UserID         *int64    `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Version        int32     `json:"version"`
+
+	LongName        string `json:"long_name,omitempty"`
+	MeasurementName string `json:"measurement_name,omitempty"`
+	UserName        string `json:"user_name,omitempty"`
+}
+
+func ValidateShoppingListItem(v *validator.Validator, item *ShoppingListItem) {
+	v.Check(item.KnownItemsID != nil || item.Text != "", "text", "must be provided when there is no knownitems_id")
+	v.Check(len(item.Text) <= 500, "text", "must not be more than 500 bytes long")
+
+	v.Check(item.Quantity > 0, "quantity", "must be greater than zero")
+	v.Check(item.Quantity <= 100000, "quantity", "must not be more than 100000 units")
+
+	if item.KnownItemsID != nil && item.Measurement == nil {
+		v.Check(item.Quantity <= 100, "quantity", "must not be more than 100 packages")
+	}
+}
+
+// GetAllForUser returns every shopping list item the user added, in any household.
+func (sli ShoppingListItemModel) GetAllForUser(userID int64) ([]*ShoppingListItem, error) {
+	query := `
+		SELECT shoppinglist_items.id, shoppinglist_items.shoppinglist_id, shoppinglist_items.knownitems_id, shoppinglist_items.text,
+		shoppinglist_items.quantity, shoppinglist_items.measurement, shoppinglist_items.checked, shoppinglist_items.user_id, shoppinglist_items.created_at, shoppinglist_items.version,
+		COALESCE(knownitems.long_name, ''), COALESCE(measurements.name, '')
+		FROM shoppinglist_items
+		LEFT JOIN knownitems ON knownitems.id = shoppinglist_items.knownitems_id
+		LEFT JOIN measurements ON measurements.id = shoppinglist_items.measurement
+		WHERE shoppinglist_items.user_id = $1
+		ORDER BY shoppinglist_items.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := sli.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []*ShoppingListItem{}
+
+	for rows.Next() {
+		var item ShoppingListItem
+
+		err := rows.Scan(
+			&item.ID,
+			&item.ShoppingListID,
+			&item.KnownItemsID,
+			&item.Text,
+			&item.Quantity,
+			&item.Measurement,
+			&item.Checked,
+			&item.UserID,
+			&item.CreatedAt,
+			&item.Version,
+			&item.LongName,
+			&item.MeasurementName,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,117 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlanAndCheckoutShoppingList(t *testing.T) {
+	measurements := map[int64]string{1: "dl", 2: "g", 3: "ml", 4: "pcs"}
+
+	required := []*RequiredIngredient{
+		{IngredientID: 1, Name: "eggs", Amount: 3, MeasurementID: 4, Measurement: "pcs"},
+		{IngredientID: 2, Name: "flour", Amount: 3, MeasurementID: 1, Measurement: "dl", Density: 0.6},
+		{IngredientID: 3, Name: "milk", Amount: 5, MeasurementID: 1, Measurement: "dl", Density: 1.03},
+		{IngredientID: 4, Name: "sugar", Amount: 100, MeasurementID: 2, Measurement: "g", Density: 0.85},
+	}
+
+	stock := []*MappedStock{
+		{IngredientID: 1, KnownItemsID: 10, MeasurementID: 4, Measurement: "pcs", Stock: 6},
+		{IngredientID: 2, KnownItemsID: 11, MeasurementID: 2, Measurement: "g", Stock: 60},
+		{IngredientID: 3, KnownItemsID: 12, MeasurementID: 3, Measurement: "ml", Stock: 200},
+		{IngredientID: 4, KnownItemsID: 13, MeasurementID: 2, Measurement: "g", Stock: 40},
+		{IngredientID: 4, KnownItemsID: 11, MeasurementID: 2, Measurement: "g", Stock: 60},
+	}
+
+	items, covered, err := PlanShoppingList(1, 1, required, stock)
+	if err != nil {
+		t.Fatalf("PlanShoppingList returned error: %v", err)
+	}
+
+	if len(covered) != 1 || covered[0].Name != "eggs" {
+		t.Errorf("covered = %v, want only eggs", covered)
+	}
+
+	// The flour is measured by volume and its known item by mass, so its stock
+	// counts through the density but the entry is free text. The sugar only gets
+	// the flour's known item stock that is left.
+	tests := []struct {
+		text         string
+		knownitemsID int64
+		quantity     float64
+		container    int32
+	}{
+		{"flour", 0, 2, 0},
+		{"", 12, 3, 300},
+		{"", 13, 60, 60},
+	}
+
+	if len(items) != len(tests) {
+		t.Fatalf("got %d items, want %d", len(items), len(tests))
+	}
+
+	for i, tt := range tests {
+		item := items[i]
+
+		if item.Text != tt.text || item.Quantity != tt.quantity {
+			t.Errorf("item %d = %q %v, want %q %v", i, item.Text, item.Quantity, tt.text, tt.quantity)
+		}
+
+		if tt.knownitemsID == 0 {
+			if item.KnownItemsID != nil {
+				t.Errorf("item %d is linked to known item %d, want free text", i, *item.KnownItemsID)
+			}
+			continue
+		}
+
+		if item.KnownItemsID == nil || *item.KnownItemsID != tt.knownitemsID {
+			t.Fatalf("item %d knownitems_id = %v, want %d", i, item.KnownItemsID, tt.knownitemsID)
+		}
+
+		var itemMeasurementID int64
+
+		for _, s := range stock {
+			if s.KnownItemsID == tt.knownitemsID {
+				itemMeasurementID = s.MeasurementID
+			}
+		}
+
+		entry := &checkoutEntry{
+			knownitemsID:      item.KnownItemsID,
+			quantity:          item.Quantity,
+			measurementID:     item.Measurement,
+			measurement:       measurements[*item.Measurement],
+			itemMeasurementID: itemMeasurementID,
+			itemMeasurement:   measurements[itemMeasurementID],
+			itemContainerSize: 500,
+		}
+
+		containers, err := checkoutContainers(entry)
+		if err != nil {
+			t.Fatalf("checking out item %d returned error: %v", i, err)
+		}
+
+		if len(containers) != 1 || containers[0] != tt.container {
+			t.Errorf("item %d checks out as %v, want [%d]", i, containers, tt.container)
+		}
+	}
+}
+
+func TestCheckoutContainersNeedsConvertibleMeasurement(t *testing.T) {
+	measurementID := int64(1)
+	knownitemsID := int64(1)
+
+	entry := &checkoutEntry{
+		knownitemsID:      &knownitemsID,
+		quantity:          3,
+		measurementID:     &measurementID,
+		measurement:       "dl",
+		itemMeasurementID: 2,
+		itemMeasurement:   "g",
+	}
+
+	_, err := checkoutContainers(entry)
+	if !errors.Is(err, ErrUnconvertibleQuantity) {
+		t.Errorf("checkoutContainers error = %v, want %v", err, ErrUnconvertibleQuantity)
+	}
+}
@@ -0,0 +1,204 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"householdingindex.homecatalogue.net/internal/validator"
+)
+
+const (
+	MovementAdd     = "add"
+	MovementConsume = "consume"
+	MovementDiscard = "discard"
+	MovementMove    = "move"
+	MovementAdjust  = "adjust"
+)
+
+// StockMovement is an entry in the append-only ledger of changes to available
+// items. QuantityChange is the change to the item's remaining quantity and
+// Remaining what was left afterwards. LocationID is where the item was kept
+// afterwards, moves also record where it came from.
+type StockMovement struct {
+	ID              int64     `json:"id"`
+	HouseholdID     int64     `json:"household_id"`
+	AvailableItemID int64     `json:"availableitems_id"`
+	KnownItemsID    int64     `json:"knownitems_id"`
+	LongName        string    `json:"long_name,omitempty"`
+	UserID          *int64    `json:"user_id"`
+	UserName        string    `json:"user_name,omitempty"`
+	Kind            string    `json:"kind"`
+	QuantityChange  int32     `json:"quantity_change"`
+	Remaining       int32     `json:"remaining"`
+	LocationID      *int64    `json:"location_id"`
+	FromLocationID  *int64    `json:"from_location_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func ValidateConsumptionAmount(v *validator.Validator, amount float64, remaining int32) {
+	v.Check(amount > 0, "amount", "must be greater than zero")
+	v.Check(amount <= float64(remaining), "amount", "must not be more than what remains of the item")
+}
+
+func ValidateStockMovementKind(v *validator.Validator, kind string) {
+	v.Check(validator.In(kind, MovementAdd, MovementConsume, MovementDiscard, MovementMove, MovementAdjust), "kind", "must be one of add, consume, discard, move or adjust")
+}
+
+func newStockMovement(availableitem *AvailableItem, userID int64, kind string, change int32) *StockMovement {
+	movement := &StockMovement{
+		HouseholdID:     availableitem.HouseholdID,
+		AvailableItemID: availableitem.ID,
+		KnownItemsID:    availableitem.KnownItemsID,
+		LocationID:      availableitem.LocationID,
+		Kind:            kind,
+		QuantityChange:  change,
+		Remaining:       availableitem.Remaining,
+	}
+
+	if userID != 0 {
+		movement.UserID = &userID
+	}
+
+	return movement
+}
+
+// insertStockMovement records the movement as part of tx, so that the ledger
+// always changes together with the item itself.
+func insertStockMovement(ctx context.Context, tx *sql.Tx, movement *StockMovement) error {
+	query := `
+		INSERT INTO stock_movements (household_id, availableitems_id, knownitems_id, user_id, kind, quantity_change, remaining, location_id, from_location_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	args := []interface{}{movement.HouseholdID, movement.AvailableItemID, movement.KnownItemsID, movement.UserID, movement.Kind, movement.QuantityChange, movement.Remaining, movement.LocationID, movement.FromLocationID}
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&movement.ID, &movement.CreatedAt)
+}
+
+type StockMovementModel struct {
+	DB *sql.DB
+}
+
+func (sm StockMovementModel) GetAll(householdID int64, availableitemsid int, knownitemsid int, userid int, kind string, since time.Time, until time.Time, filters Filters) ([]*StockMovement, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), stock_movements.id, stock_movements.household_id, stock_movements.availableitems_id, stock_movements.knownitems_id, COALESCE(knownitems.long_name, ''),
+		stock_movements.user_id, COALESCE(users.name, ''), stock_movements.kind, stock_movements.quantity_change, stock_movements.remaining, stock_movements.location_id, stock_movements.from_location_id, stock_movements.created_at
+		FROM stock_movements
+		LEFT JOIN knownitems ON knownitems.id = stock_movements.knownitems_id
+		LEFT JOIN users ON users.id = stock_movements.user_id
+		WHERE stock_movements.household_id = $1
+		AND (stock_movements.availableitems_id = $2 OR $2 = 0)
+		AND (stock_movements.knownitems_id = $3 OR $3 = 0)
+		AND (stock_movements.user_id = $4 OR $4 = 0)
+		AND (stock_movements.kind = $5 OR $5 = '')
+		AND (stock_movements.created_at >= $6 OR $6 = '0001-01-01T00:00:00Z')
+		AND (stock_movements.created_at < $7 OR $7 = '0001-01-01T00:00:00Z')
+		ORDER BY stock_movements.%s %s, stock_movements.id ASC
+		LIMIT $8 OFFSET $9`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{householdID, availableitemsid, knownitemsid, userid, kind, since.Format(time.RFC3339), until.Format(time.RFC3339), filters.limit(), filters.offset()}
+
+	rows, err := sm.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	movements := []*StockMovement{}
+
+	for rows.Next() {
+		var movement StockMovement
+
+		err := rows.Scan(
+			&totalRecords,
+			&movement.ID,
+			&movement.HouseholdID,
+			&movement.AvailableItemID,
+			&movement.KnownItemsID,
+			&movement.LongName,
+			&movement.UserID,
+			&movement.UserName,
+			&movement.Kind,
+			&movement.QuantityChange,
+			&movement.Remaining,
+			&movement.LocationID,
+			&movement.FromLocationID,
+			&movement.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movements = append(movements, &movement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movements, metadata, nil
+}
+
+// GetAllForUser returns every movement the user recorded, in any household.
+func (sm StockMovementModel) GetAllForUser(userID int64) ([]*StockMovement, error) {
+	query := `
+		SELECT stock_movements.id, stock_movements.household_id, stock_movements.availableitems_id, stock_movements.knownitems_id, COALESCE(knownitems.long_name, ''),
+		stock_movements.user_id, stock_movements.kind, stock_movements.quantity_change, stock_movements.remaining, stock_movements.location_id, stock_movements.from_location_id, stock_movements.created_at
+		FROM stock_movements
+		LEFT JOIN knownitems ON knownitems.id = stock_movements.knownitems_id
+		WHERE stock_movements.user_id = $1
+		ORDER BY stock_movements.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := sm.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movements := []*StockMovement{}
+
+	for rows.Next() {
+		var movement StockMovement
+
+		err := rows.Scan(
+			&movement.ID,
+			&movement.HouseholdID,
+			&movement.AvailableItemID,
+			&movement.KnownItemsID,
+			&movement.LongName,
+			&movement.UserID,
+			&movement.Kind,
+			&movement.QuantityChange,
+			&movement.Remaining,
+			&movement.LocationID,
+			&movement.FromLocationID,
+			&movement.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		movements = append(movements, &movement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
@@ -6,29 +6,43 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"time"
 
 	"householdingindex.homecatalogue.net/internal/validator"
 )
 
 const (
-	ScopeActivation     = "activation"
-	ScopeAuthentication = "authentication"
+	ScopeActivation          = "activation"
+	ScopeAuthentication      = "authentication"
+	ScopeHouseholdInvitation = "household-invitation"
+	ScopePasswordReset       = "password-reset"
+	ScopeRefresh             = "refresh"
+)
+
+var (
+	ErrTokenReused = errors.New("token reused")
 )
 
 type Token struct {
-	Plaintext string    `json:"token"`
-	Hash      []byte    `json:"-"`
-	UserID    int64     `json:"-"`
-	Expiry    time.Time `json:"expiry"`
-	Scope     string    `json:"-"`
+	Plaintext  string     `json:"token,omitempty"`
+	Hash       []byte     `json:"-"`
+	UserID     int64      `json:"-"`
+	Expiry     time.Time  `json:"expiry"`
+	Scope      string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	Family     []byte     `json:"-"`
+	Rotated    bool       `json:"-"`
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token := &Token{
-		UserID: userID,
-		Expiry: time.Now().Add(ttl),
-		Scope:  scope,
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+		CreatedAt: time.Now(),
 	}
 
 	randomBytes := make([]byte, 16)
@@ -66,12 +80,53 @@ func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token,
 	return token, err
 }
 
+// NewSession issues a short-lived authentication token together with the refresh
+// token that can be exchanged for the next one. Both belong to the given token
+// family, or to a new family when family is nil.
+func (m TokenModel) NewSession(userID int64, family []byte, authenticationTTL, refreshTTL time.Duration, userAgent string) (*Token, *Token, error) {
+	if family == nil {
+		family = make([]byte, 16)
+
+		_, err := rand.Read(family)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	authentication, err := generateToken(userID, authenticationTTL, ScopeAuthentication)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refresh, err := generateToken(userID, refreshTTL, ScopeRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, token := range []*Token{authentication, refresh} {
+		token.UserAgent = userAgent
+		token.Family = family
+
+		err = m.Insert(token)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return authentication, refresh, nil
+}
+
 func (m TokenModel) Insert(token *Token) error {
 	query := `
-		INSERT INTO tokens (hash, user_id, expiry, scope)
-		VALUES ($1, $2, $3, $4)`
+		INSERT INTO tokens (hash, user_id, expiry, scope, created_at, user_agent, family)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	var family interface{}
+	if len(token.Family) > 0 {
+		family = token.Family
+	}
 
-	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
+	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope, token.CreatedAt, token.UserAgent, family}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -93,3 +148,165 @@ func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
 
 	return err
 }
+
+// Delete removes the token along with every other token of its family, so that
+// signing out also revokes the refresh token the session was issued with.
+func (m TokenModel) Delete(scope string, tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		DELETE FROM tokens
+		WHERE (scope = $1 AND hash = $2)
+		OR family = (SELECT family FROM tokens WHERE scope = $1 AND hash = $2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, scope, tokenHash[:])
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m TokenModel) GetAllForUser(scope string, userID int64) ([]*Token, error) {
+	query := `
+		SELECT hash, user_id, expiry, scope, created_at, last_used_at, user_agent
+		FROM tokens
+		WHERE scope = $1 AND user_id = $2 AND expiry > $3
+		ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, scope, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tokens := []*Token{}
+
+	for rows.Next() {
+		var token Token
+
+		err := rows.Scan(
+			&token.Hash,
+			&token.UserID,
+			&token.Expiry,
+			&token.Scope,
+			&token.CreatedAt,
+			&token.LastUsedAt,
+			&token.UserAgent,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, &token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// UpdateLastUsed records that the token was just used. To avoid a write on every
+// request the timestamp is only moved forward once a minute.
+func (m TokenModel) UpdateLastUsed(scope string, tokenPlaintext string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		UPDATE tokens
+		SET last_used_at = NOW()
+		WHERE scope = $1 AND hash = $2
+		AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, scope, tokenHash[:])
+
+	return err
+}
+
+// Rotate marks a refresh token as used and returns it. A token that was already
+// rotated is reported with ErrTokenReused, which means it has leaked.
+func (m TokenModel) Rotate(scope string, tokenPlaintext string) (*Token, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		WITH previous AS (
+			SELECT hash, rotated
+			FROM tokens
+			WHERE scope = $1 AND hash = $2 AND expiry > $3
+			FOR UPDATE
+		)
+		UPDATE tokens
+		SET rotated = true, last_used_at = NOW()
+		FROM previous
+		WHERE tokens.hash = previous.hash
+		RETURNING tokens.hash, tokens.user_id, tokens.expiry, tokens.scope, tokens.created_at, tokens.user_agent, tokens.family, previous.rotated`
+
+	var token Token
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, scope, tokenHash[:], time.Now()).Scan(
+		&token.Hash,
+		&token.UserID,
+		&token.Expiry,
+		&token.Scope,
+		&token.CreatedAt,
+		&token.UserAgent,
+		&token.Family,
+		&token.Rotated,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if token.Rotated {
+		return &token, ErrTokenReused
+	}
+
+	return &token, nil
+}
+
+func (m TokenModel) DeleteFamily(family []byte) error {
+	if len(family) == 0 {
+		return nil
+	}
+
+	query := `
+		DELETE FROM tokens
+		WHERE family = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, family)
+
+	return err
+}
@@ -9,6 +9,7 @@ import (
 
 	"householdingindex.homecatalogue.net/internal/validator"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,20 +19,35 @@ var (
 
 var AnonymousUser = &User{}
 
+const (
+	LoginLockoutThreshold = 5
+	LoginLockoutBase      = time.Minute
+	LoginLockoutMax       = 24 * time.Hour
+)
+
 type User struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Activated bool      `json:"activated"`
-	Version   int       `json:"-"`
+	ID                  int64      `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	PendingEmail        string     `json:"pending_email,omitempty"`
+	Password            password   `json:"-"`
+	Activated           bool       `json:"activated"`
+	HouseholdID         int64      `json:"household_id,omitempty"`
+	HouseholdRole       string     `json:"household_role,omitempty"`
+	LockedUntil         *time.Time `json:"-"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	Version             int        `json:"version"`
 }
 
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(time.Now())
+}
+
 type password struct {
 	plaintext *string
 	hash      []byte
@@ -66,9 +82,11 @@ func (m UserModel) Insert(user *User) error {
 
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-		SELECT id, created_at, name, email, password_hash, activated, version
+		SELECT users.id, users.created_at, users.name, users.email, users.pending_email, users.password_hash, users.activated, users.version, users.locked_until, users.deletion_scheduled_at, COALESCE(households_users.household_id, 0), COALESCE(households_users.role, '')
 		FROM users
-		WHERE email = $1`
+		LEFT JOIN households_users
+		ON users.id = households_users.user_id
+		WHERE users.email = $1`
 
 	var user User
 
@@ -80,9 +98,58 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.CreatedAt,
 		&user.Name,
 		&user.Email,
+		&user.PendingEmail,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+		&user.LockedUntil,
+		&user.DeletionScheduledAt,
+		&user.HouseholdID,
+		&user.HouseholdRole,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (m UserModel) Get(id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT users.id, users.created_at, users.name, users.email, users.pending_email, users.password_hash, users.activated, users.version, users.locked_until, users.deletion_scheduled_at, COALESCE(households_users.household_id, 0), COALESCE(households_users.role, '')
+		FROM users
+		LEFT JOIN households_users
+		ON users.id = households_users.user_id
+		WHERE users.id = $1`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.PendingEmail,
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.LockedUntil,
+		&user.DeletionScheduledAt,
+		&user.HouseholdID,
+		&user.HouseholdRole,
 	)
 
 	if err != nil {
@@ -100,13 +167,14 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 func (m UserModel) Update(user *User) error {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-		WHERE id = $5 AND version = $6
+		SET name = $1, email = $2, pending_email = $3, password_hash = $4, activated = $5, version = version + 1
+		WHERE id = $6 AND version = $7
 		RETURNING version`
 
 	args := []interface{}{
 		user.Name,
 		user.Email,
+		user.PendingEmail,
 		user.Password.hash,
 		user.Activated,
 		user.ID,
@@ -131,6 +199,208 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
+// RecordFailedLogin counts a failed login for the user. Once LoginLockoutThreshold
+// failures in a row are reached the account is locked, for a period that doubles
+// with every further failure up to LoginLockoutMax. The doubling stops after 20
+// failures so the interval cannot overflow however often a login fails. It
+// returns the time the account is locked until, or nil if it was not locked.
+func (m UserModel) RecordFailedLogin(userID int64) (*time.Time, error) {
+	query := `
+		UPDATE users
+		SET failed_logins = failed_logins + 1,
+		locked_until = CASE
+			WHEN failed_logins + 1 >= $2
+			THEN NOW() + LEAST(make_interval(secs => $3 * power(2, LEAST(failed_logins + 1 - $2, 20))), make_interval(secs => $4))
+			ELSE locked_until
+		END
+		WHERE id = $1
+		RETURNING CASE WHEN failed_logins >= $2 THEN locked_until END`
+
+	args := []interface{}{userID, LoginLockoutThreshold, LoginLockoutBase.Seconds(), LoginLockoutMax.Seconds()}
+
+	var lockedUntil *time.Time
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&lockedUntil)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return lockedUntil, nil
+}
+
+func (m UserModel) ResetFailedLogins(userID int64) error {
+	query := `
+		UPDATE users
+		SET failed_logins = 0, locked_until = NULL
+		WHERE id = $1 AND (failed_logins > 0 OR locked_until IS NOT NULL)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+
+	return err
+}
+
+// ScheduleDeletion marks the user's account to be purged at the given time, unless
+// the deletion is cancelled before then.
+func (m UserModel) ScheduleDeletion(userID int64, at time.Time) error {
+	query := `
+		UPDATE users
+		SET deletion_scheduled_at = $1
+		WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, at, userID)
+
+	return err
+}
+
+func (m UserModel) CancelDeletion(userID int64) error {
+	query := `
+		UPDATE users
+		SET deletion_scheduled_at = NULL
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+
+	return err
+}
+
+// PurgeScheduledDeletions deletes every account whose scheduled deletion time has
+// passed, along with any household that has no members left afterwards. A
+// household that would be left without an owner gets a new one. It returns the
+// number of accounts deleted.
+func (m UserModel) PurgeScheduledDeletions() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	query := `
+		SELECT COALESCE(array_agg(DISTINCT households_users.household_id), '{}')
+		FROM households_users
+		INNER JOIN users ON households_users.user_id = users.id
+		WHERE users.deletion_scheduled_at <= $1`
+
+	var households []int64
+
+	err = tx.QueryRowContext(ctx, query, now).Scan(pq.Array(&households))
+	if err != nil {
+		return 0, err
+	}
+
+	// Deletion is refused to the sole owner of a household with other members, but
+	// owners can still leave or be deleted in the meantime. So that no household
+	// is left without an owner, the remaining member who has been there longest is
+	// promoted, members before viewers.
+	query = `
+		UPDATE households_users
+		SET role = $3
+		WHERE (household_id, user_id) IN (
+			SELECT DISTINCT ON (households_users.household_id) households_users.household_id, households_users.user_id
+			FROM households_users
+			INNER JOIN users ON users.id = households_users.user_id
+			WHERE households_users.household_id = ANY($1)
+			AND (users.deletion_scheduled_at IS NULL OR users.deletion_scheduled_at > $2)
+			AND NOT EXISTS (
+				SELECT 1
+				FROM households_users owners
+				INNER JOIN users owner_users ON owner_users.id = owners.user_id
+				WHERE owners.household_id = households_users.household_id
+				AND owners.role = $3
+				AND (owner_users.deletion_scheduled_at IS NULL OR owner_users.deletion_scheduled_at > $2)
+			)
+			ORDER BY households_users.household_id, households_users.role = $4 DESC, households_users.created_at, households_users.user_id
+		)`
+
+	_, err = tx.ExecContext(ctx, query, pq.Array(households), now, RoleOwner, RoleMember)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE deletion_scheduled_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		SELECT households.id
+		FROM households
+		WHERE households.id = ANY($1)
+		AND NOT EXISTS (SELECT 1 FROM households_users WHERE households_users.household_id = households.id)`
+
+	var empty []int64
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(households))
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+
+		empty = append(empty, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(empty) > 0 {
+		// recipe_ingredients are not owned by a household directly, so they would
+		// otherwise block the cascade from households to recipies and ingredients.
+		query = `
+			DELETE FROM recipe_ingredients
+			WHERE recipe_id IN (SELECT id FROM recipies WHERE household_id = ANY($1))
+			OR ingredient_id IN (SELECT id FROM ingredients WHERE household_id = ANY($1))`
+
+		_, err = tx.ExecContext(ctx, query, pq.Array(empty))
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM households WHERE id = ANY($1)`, pq.Array(empty))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return deleted, tx.Commit()
+}
+
 func (p *password) Set(plaintextPassword string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
 	if err != nil {
@@ -187,10 +457,12 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+		SELECT users.id, users.created_at, users.name, users.email, users.pending_email, users.password_hash, users.activated, users.version, users.locked_until, users.deletion_scheduled_at, COALESCE(households_users.household_id, 0), COALESCE(households_users.role, '')
 		FROM users
 		INNER JOIN tokens
 		ON users.id = tokens.user_id
+		LEFT JOIN households_users
+		ON users.id = households_users.user_id
 		WHERE tokens.hash = $1
 		AND tokens.scope = $2
 		AND tokens.expiry > $3`
@@ -207,9 +479,14 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.CreatedAt,
 		&user.Name,
 		&user.Email,
+		&user.PendingEmail,
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.LockedUntil,
+		&user.DeletionScheduledAt,
+		&user.HouseholdID,
+		&user.HouseholdRole,
 	)
 
 	if err != nil {
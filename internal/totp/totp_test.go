@@ -0,0 +1,148 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// The RFC 4226 and RFC 6238 test secret "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 Appendix D.
+	expected := []string{
+		"755224",
+		"287082",
+		"359152",
+		"969429",
+		"338314",
+		"254676",
+		"287922",
+		"162583",
+		"399871",
+		"520489",
+	}
+
+	key := []byte("12345678901234567890")
+
+	for counter, want := range expected {
+		if got := hotp(key, uint64(counter)); got != want {
+			t.Errorf("hotp(%d) = %q, want %q", counter, got, want)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	// RFC 6238 Appendix B SHA-1 vectors. The RFC uses 8 digits, the last 6 of
+	// which are the 6 digit code.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d) returned error: %v", tt.unix, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("Code(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / int64(Period/time.Second)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		valid  bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -Period, true},
+		{"next step", Period, true},
+		{"two steps back", -2 * Period, false},
+		{"two steps ahead", 2 * Period, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, now.Add(tt.offset))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			matched, ok := Validate(rfcSecret, code, now)
+			if ok != tt.valid {
+				t.Fatalf("Validate() ok = %v, want %v", ok, tt.valid)
+			}
+
+			if ok && matched != step+int64(tt.offset/Period) {
+				t.Errorf("Validate() step = %d, want %d", matched, step+int64(tt.offset/Period))
+			}
+		})
+	}
+}
+
+func TestValidateAfterRejectsReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / int64(Period/time.Second)
+
+	code, err := Code(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		lastStep int64
+		valid    bool
+	}{
+		{"never used", 0, true},
+		{"earlier step used", step - 1, true},
+		{"same step used", step, false},
+		{"later step used", step + 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := ValidateAfter(rfcSecret, code, now, tt.lastStep)
+			if ok != tt.valid {
+				t.Errorf("ValidateAfter() ok = %v, want %v", ok, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformed(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfcSecret, "000000"},
+		{"short code", rfcSecret, "12345"},
+		{"long code", rfcSecret, "1234567"},
+		{"invalid secret", "not base32!", "123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, now); ok {
+				t.Errorf("Validate(%q, %q) ok = true, want false", tt.secret, tt.code)
+			}
+		})
+	}
+}
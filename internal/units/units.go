@@ -0,0 +1,207 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+var (
+	ErrUnknownUnit       = errors.New("unknown unit")
+	ErrIncompatibleUnits = errors.New("incompatible units")
+	ErrMissingDensity    = errors.New("a density is needed to convert between mass and volume")
+)
+
+type Dimension string
+
+const (
+	Mass   Dimension = "mass"
+	Volume Dimension = "volume"
+	Count  Dimension = "count"
+)
+
+// Unit is a unit of measurement. Factor converts an amount in the unit to the
+// base unit of its dimension: grams for mass, milliliters for volume and single
+// units for count. Amounts in units with a step are rounded to a multiple of it,
+// the others to three significant figures.
+type Unit struct {
+	Name      string    `json:"name"`
+	Symbol    string    `json:"symbol"`
+	Dimension Dimension `json:"dimension"`
+	Factor    float64   `json:"factor"`
+	step      float64
+}
+
+var known = []Unit{
+	{"milligrams", "mg", Mass, 0.001, 0},
+	{"grams", "g", Mass, 1, 0},
+	{"hectograms", "hg", Mass, 100, 0},
+	{"kilograms", "kg", Mass, 1000, 0},
+	{"ounces", "oz", Mass, 28.349523125, 0},
+	{"pounds", "lb", Mass, 453.59237, 0},
+
+	{"milliliters", "ml", Volume, 1, 0},
+	{"centiliters", "cl", Volume, 10, 0},
+	{"deciliters", "dl", Volume, 100, 0},
+	{"liters", "l", Volume, 1000, 0},
+	{"teaspoons", "tsp", Volume, 5, 0.25},
+	{"tablespoons", "tbsp", Volume, 15, 0.25},
+	{"cups", "cup", Volume, 240, 0.25},
+
+	{"units", "x", Count, 1, 0.5},
+	{"dozens", "doz", Count, 12, 0.5},
+}
+
+// promotions lists the larger units an amount in a unit may be given in, from
+// the largest down, keeping metric and kitchen measures apart.
+var promotions = map[string][]string{
+	"milligrams":  {"kilograms", "grams"},
+	"grams":       {"kilograms"},
+	"hectograms":  {"kilograms"},
+	"ounces":      {"pounds"},
+	"milliliters": {"liters"},
+	"centiliters": {"liters"},
+	"deciliters":  {"liters"},
+	"teaspoons":   {"cups", "tablespoons"},
+	"tablespoons": {"cups"},
+}
+
+var aliases = map[string]string{
+	"unit":        "units",
+	"pcs":         "units",
+	"pieces":      "units",
+	"piece":       "units",
+	"st":          "units",
+	"litres":      "liters",
+	"litre":       "liters",
+	"millilitres": "milliliters",
+	"millilitre":  "milliliters",
+	"centilitres": "centiliters",
+	"centilitre":  "centiliters",
+	"decilitres":  "deciliters",
+	"decilitre":   "deciliters",
+	"msk":         "tablespoons",
+	"tsk":         "teaspoons",
+	"lbs":         "pounds",
+}
+
+// Lookup finds a unit by its name, singular name, symbol or a common alias,
+// ignoring case.
+func Lookup(name string) (Unit, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+
+	for _, unit := range known {
+		if name == unit.Name || name == unit.Symbol || name+"s" == unit.Name {
+			return unit, nil
+		}
+	}
+
+	return Unit{}, ErrUnknownUnit
+}
+
+// Convert converts an amount between two units of the same dimension.
+func Convert(amount float64, from, to Unit) (float64, error) {
+	if from.Dimension != to.Dimension {
+		return 0, ErrIncompatibleUnits
+	}
+
+	return amount * from.Factor / to.Factor, nil
+}
+
+// ConvertDensity converts an amount between two units like Convert, and also
+// between mass and volume given the density in grams per milliliter. A density
+// of zero means it is unknown.
+func ConvertDensity(amount float64, from, to Unit, density float64) (float64, error) {
+	if from.Dimension == to.Dimension {
+		return Convert(amount, from, to)
+	}
+
+	base := amount * from.Factor
+
+	switch {
+	case from.Dimension == Volume && to.Dimension == Mass:
+		if density <= 0 {
+			return 0, ErrMissingDensity
+		}
+
+		base *= density
+	case from.Dimension == Mass && to.Dimension == Volume:
+		if density <= 0 {
+			return 0, ErrMissingDensity
+		}
+
+		base /= density
+	default:
+		return 0, ErrIncompatibleUnits
+	}
+
+	return base / to.Factor, nil
+}
+
+// Names returns every lowercase name Lookup accepts, mapped to its unit.
+func Names() map[string]Unit {
+	names := make(map[string]Unit)
+
+	for _, unit := range known {
+		names[unit.Name] = unit
+		names[strings.TrimSuffix(unit.Name, "s")] = unit
+		names[strings.ToLower(unit.Symbol)] = unit
+	}
+
+	for alias, name := range aliases {
+		names[alias] = names[name]
+	}
+
+	return names
+}
+
+// Round rounds an amount to what makes sense for the unit. An amount above zero
+// is never rounded down to zero.
+func Round(amount float64, unit Unit) float64 {
+	if amount <= 0 {
+		return 0
+	}
+
+	if unit.step > 0 {
+		return math.Max(math.Round(amount/unit.step)*unit.step, unit.step)
+	}
+
+	exp := 2 - int(math.Floor(math.Log10(amount)))
+
+	if exp >= 0 {
+		p := math.Pow10(exp)
+		return math.Round(amount*p) / p
+	}
+
+	p := math.Pow10(-exp)
+	return math.Round(amount/p) * p
+}
+
+// Simplify gives a rounded amount in the largest unit that still shows at least
+// one of it and stays within a percent of the exact amount, for example 1000
+// grams as 1 kilogram but 4 teaspoons as 4 teaspoons rather than 1.25
+// tablespoons.
+func Simplify(amount float64, unit Unit) (float64, Unit) {
+	for _, name := range promotions[unit.Name] {
+		larger, err := Lookup(name)
+		if err != nil {
+			continue
+		}
+
+		converted := amount * unit.Factor / larger.Factor
+		if converted < 1 {
+			continue
+		}
+
+		rounded := Round(converted, larger)
+		if math.Abs(rounded-converted) <= converted*0.01 {
+			return rounded, larger
+		}
+	}
+
+	return Round(amount, unit), unit
+}
@@ -0,0 +1,199 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func lookup(t *testing.T, name string) Unit {
+	t.Helper()
+
+	unit, err := Lookup(name)
+	if err != nil {
+		t.Fatalf("Lookup(%q) returned error: %v", name, err)
+	}
+
+	return unit
+}
+
+func TestLookup(t *testing.T) {
+	for _, unit := range known {
+		singular := strings.TrimSuffix(unit.Name, "s")
+
+		for _, name := range []string{unit.Name, singular, unit.Symbol, strings.ToUpper(unit.Name), " " + unit.Symbol + " "} {
+			got, err := Lookup(name)
+			if err != nil {
+				t.Errorf("Lookup(%q) returned error: %v", name, err)
+				continue
+			}
+
+			if got.Name != unit.Name {
+				t.Errorf("Lookup(%q) = %q, want %q", name, got.Name, unit.Name)
+			}
+		}
+	}
+}
+
+func TestLookupAliases(t *testing.T) {
+	tests := []struct {
+		alias string
+		want  string
+	}{
+		{"unit", "units"},
+		{"pcs", "units"},
+		{"pieces", "units"},
+		{"piece", "units"},
+		{"st", "units"},
+		{"litres", "liters"},
+		{"litre", "liters"},
+		{"millilitres", "milliliters"},
+		{"millilitre", "milliliters"},
+		{"centilitres", "centiliters"},
+		{"centilitre", "centiliters"},
+		{"decilitres", "deciliters"},
+		{"decilitre", "deciliters"},
+		{"msk", "tablespoons"},
+		{"tsk", "teaspoons"},
+		{"lbs", "pounds"},
+		{"MSK", "tablespoons"},
+	}
+
+	for _, tt := range tests {
+		if got := lookup(t, tt.alias); got.Name != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.alias, got.Name, tt.want)
+		}
+	}
+
+	for alias := range aliases {
+		if _, err := Lookup(alias); err != nil {
+			t.Errorf("Lookup(%q) returned error: %v", alias, err)
+		}
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	for _, name := range []string{"", "handful", "pinch", "s"} {
+		if _, err := Lookup(name); !errors.Is(err, ErrUnknownUnit) {
+			t.Errorf("Lookup(%q) error = %v, want %v", name, err, ErrUnknownUnit)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	for name, unit := range Names() {
+		got := lookup(t, name)
+		if got.Name != unit.Name {
+			t.Errorf("Names()[%q] = %q, but Lookup gives %q", name, unit.Name, got.Name)
+		}
+	}
+}
+
+func TestConvertDensity(t *testing.T) {
+	tests := []struct {
+		amount  float64
+		from    string
+		to      string
+		density float64
+		want    float64
+		err     error
+	}{
+		{1, "kg", "g", 0, 1000, nil},
+		{2, "tbsp", "tsp", 0, 6, nil},
+		{1, "l", "dl", 0, 10, nil},
+		{1, "lb", "oz", 0, 16, nil},
+		{2, "doz", "pcs", 0, 24, nil},
+		{250, "ml", "g", 1.03, 257.5, nil},
+		{1, "dl", "g", 0.6, 60, nil},
+		{500, "g", "dl", 0.5, 10, nil},
+		{1, "kg", "l", 0.92, 1000 / 0.92 / 1000, nil},
+		{1, "cup", "g", 0, 0, ErrMissingDensity},
+		{100, "g", "ml", 0, 0, ErrMissingDensity},
+		{100, "g", "ml", -1, 0, ErrMissingDensity},
+		{3, "units", "g", 1, 0, ErrIncompatibleUnits},
+		{100, "g", "pcs", 1, 0, ErrIncompatibleUnits},
+		{1, "l", "doz", 1, 0, ErrIncompatibleUnits},
+	}
+
+	for _, tt := range tests {
+		got, err := ConvertDensity(tt.amount, lookup(t, tt.from), lookup(t, tt.to), tt.density)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ConvertDensity(%v %s to %s, %v) error = %v, want %v", tt.amount, tt.from, tt.to, tt.density, err, tt.err)
+			continue
+		}
+
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ConvertDensity(%v %s to %s, %v) = %v, want %v", tt.amount, tt.from, tt.to, tt.density, got, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	if _, err := Convert(1, lookup(t, "l"), lookup(t, "kg")); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Errorf("Convert(l to kg) error = %v, want %v", err, ErrIncompatibleUnits)
+	}
+
+	got, err := Convert(3, lookup(t, "tsp"), lookup(t, "tbsp"))
+	if err != nil {
+		t.Fatalf("Convert(tsp to tbsp) returned error: %v", err)
+	}
+
+	if got != 1 {
+		t.Errorf("Convert(3 tsp to tbsp) = %v, want 1", got)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount float64
+		unit   Unit
+		want   float64
+	}{
+		{1234, lookup(t, "g"), 1230},
+		{1.2345, lookup(t, "kg"), 1.23},
+		{0.012345, lookup(t, "l"), 0.0123},
+		{1.1, lookup(t, "tsp"), 1},
+		{1.2, lookup(t, "tbsp"), 1.25},
+		{0.1, lookup(t, "units"), 0.5},
+		{2.7, lookup(t, "units"), 2.5},
+		{0.01, lookup(t, "cup"), 0.25},
+		{0, lookup(t, "g"), 0},
+		{-1, lookup(t, "units"), 0},
+		{1234.5, Unit{Name: "handfuls"}, 1230},
+		{0.98765, Unit{Name: "handfuls"}, 0.988},
+	}
+
+	for _, tt := range tests {
+		if got := Round(tt.amount, tt.unit); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Round(%v %s) = %v, want %v", tt.amount, tt.unit.Name, got, tt.want)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		unit     Unit
+		want     float64
+		wantUnit string
+	}{
+		{1000, lookup(t, "g"), 1, "kilograms"},
+		{1234, lookup(t, "g"), 1.23, "kilograms"},
+		{999, lookup(t, "g"), 999, "grams"},
+		{4, lookup(t, "tsp"), 4, "teaspoons"},
+		{3, lookup(t, "tsp"), 1, "tablespoons"},
+		{48, lookup(t, "tsp"), 1, "cups"},
+		{0.1, lookup(t, "units"), 0.5, "units"},
+		{1500, lookup(t, "ml"), 1.5, "liters"},
+		{16, lookup(t, "oz"), 1, "pounds"},
+		{12.3456, Unit{Name: "handfuls"}, 12.3, "handfuls"},
+	}
+
+	for _, tt := range tests {
+		got, unit := Simplify(tt.amount, tt.unit)
+		if math.Abs(got-tt.want) > 1e-9 || unit.Name != tt.wantUnit {
+			t.Errorf("Simplify(%v %s) = %v %s, want %v %s", tt.amount, tt.unit.Name, got, unit.Name, tt.want, tt.wantUnit)
+		}
+	}
+}
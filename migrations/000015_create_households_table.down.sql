@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS households_users;
+
+DROP TABLE IF EXISTS households;
\ No newline at end of file
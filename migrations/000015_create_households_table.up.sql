@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS households (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS households_users (
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    user_id bigint NOT NULL UNIQUE REFERENCES users ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (household_id, user_id)
+);
\ No newline at end of file
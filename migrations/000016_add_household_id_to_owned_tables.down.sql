@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS knownitems_household_id_idx;
+
+DROP INDEX IF EXISTS availableitems_household_id_idx;
+
+DROP INDEX IF EXISTS recipies_household_id_idx;
+
+DROP INDEX IF EXISTS ingredients_household_id_idx;
+
+ALTER TABLE knownitems DROP CONSTRAINT IF EXISTS knownitems_household_serial_number_long_name_key;
+
+ALTER TABLE knownitems ADD CONSTRAINT knownitems_serial_number_long_name_key UNIQUE (serial_number, long_name);
+
+ALTER TABLE knownitems DROP COLUMN IF EXISTS household_id;
+
+ALTER TABLE availableitems DROP COLUMN IF EXISTS household_id;
+
+ALTER TABLE recipies DROP COLUMN IF EXISTS household_id;
+
+ALTER TABLE ingredients DROP COLUMN IF EXISTS household_id;
\ No newline at end of file
@@ -0,0 +1,45 @@
+ALTER TABLE knownitems ADD COLUMN IF NOT EXISTS household_id bigint REFERENCES households ON DELETE CASCADE;
+
+ALTER TABLE availableitems ADD COLUMN IF NOT EXISTS household_id bigint REFERENCES households ON DELETE CASCADE;
+
+ALTER TABLE recipies ADD COLUMN IF NOT EXISTS household_id bigint REFERENCES households ON DELETE CASCADE;
+
+ALTER TABLE ingredients ADD COLUMN IF NOT EXISTS household_id bigint REFERENCES households ON DELETE CASCADE;
+
+/*
+    Everything created before households existed was shared by all users,
+    so it is moved into a single default household that every existing user joins.
+*/
+INSERT INTO households (name)
+VALUES ('Default household');
+
+INSERT INTO households_users (household_id, user_id)
+SELECT (SELECT min(id) FROM households), users.id FROM users;
+
+UPDATE knownitems SET household_id = (SELECT min(id) FROM households);
+
+UPDATE availableitems SET household_id = (SELECT min(id) FROM households);
+
+UPDATE recipies SET household_id = (SELECT min(id) FROM households);
+
+UPDATE ingredients SET household_id = (SELECT min(id) FROM households);
+
+ALTER TABLE knownitems ALTER COLUMN household_id SET NOT NULL;
+
+ALTER TABLE availableitems ALTER COLUMN household_id SET NOT NULL;
+
+ALTER TABLE recipies ALTER COLUMN household_id SET NOT NULL;
+
+ALTER TABLE ingredients ALTER COLUMN household_id SET NOT NULL;
+
+ALTER TABLE knownitems DROP CONSTRAINT IF EXISTS knownitems_serial_number_long_name_key;
+
+ALTER TABLE knownitems ADD CONSTRAINT knownitems_household_serial_number_long_name_key UNIQUE (household_id, serial_number, long_name);
+
+CREATE INDEX IF NOT EXISTS knownitems_household_id_idx ON knownitems (household_id);
+
+CREATE INDEX IF NOT EXISTS availableitems_household_id_idx ON availableitems (household_id);
+
+CREATE INDEX IF NOT EXISTS recipies_household_id_idx ON recipies (household_id);
+
+CREATE INDEX IF NOT EXISTS ingredients_household_id_idx ON ingredients (household_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS household_invitations;
+
+ALTER TABLE households_users DROP CONSTRAINT IF EXISTS households_users_role_check;
+
+ALTER TABLE households_users DROP COLUMN IF EXISTS role;
\ No newline at end of file
@@ -0,0 +1,18 @@
+ALTER TABLE households_users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'member';
+
+/*
+    Members of existing households keep full access to their data.
+*/
+UPDATE households_users SET role = 'owner';
+
+ALTER TABLE households_users ADD CONSTRAINT households_users_role_check CHECK (role IN ('owner', 'member', 'viewer'));
+
+CREATE TABLE IF NOT EXISTS household_invitations (
+    token_hash bytea PRIMARY KEY REFERENCES tokens(hash) ON DELETE CASCADE,
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    invited_by bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE household_invitations ADD CONSTRAINT household_invitations_role_check CHECK (role IN ('owner', 'member', 'viewer'));
\ No newline at end of file
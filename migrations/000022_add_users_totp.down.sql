@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
\ No newline at end of file
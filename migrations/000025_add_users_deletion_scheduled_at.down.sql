@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_deletion_scheduled_at_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_at;
\ No newline at end of file
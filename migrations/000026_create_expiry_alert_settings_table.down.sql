@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS availableitems_household_id_expiration_at_idx;
+
+DROP TABLE IF EXISTS expiry_alert_settings;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS expiry_alert_settings (
+    user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    enabled bool NOT NULL DEFAULT false,
+    lead_days integer NOT NULL DEFAULT 3,
+    delivery_hour integer NOT NULL DEFAULT 8,
+    last_sent_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE expiry_alert_settings ADD CONSTRAINT expiry_alert_settings_lead_days_check CHECK (lead_days BETWEEN 0 AND 60);
+
+ALTER TABLE expiry_alert_settings ADD CONSTRAINT expiry_alert_settings_delivery_hour_check CHECK (delivery_hour BETWEEN 0 AND 23);
+
+CREATE INDEX IF NOT EXISTS availableitems_household_id_expiration_at_idx ON availableitems (household_id, expiration_at);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS consumptions;
+
+ALTER TABLE availableitems DROP CONSTRAINT IF EXISTS availableitems_remaining_check;
+
+ALTER TABLE availableitems DROP COLUMN IF EXISTS remaining;
\ No newline at end of file
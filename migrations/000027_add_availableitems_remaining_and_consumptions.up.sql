@@ -0,0 +1,21 @@
+ALTER TABLE availableitems ADD COLUMN IF NOT EXISTS remaining integer;
+
+UPDATE availableitems SET remaining = container_size WHERE remaining IS NULL;
+
+ALTER TABLE availableitems ALTER COLUMN remaining SET NOT NULL;
+
+ALTER TABLE availableitems ADD CONSTRAINT availableitems_remaining_check CHECK (remaining >= 0);
+
+CREATE TABLE IF NOT EXISTS consumptions (
+    id bigserial PRIMARY KEY,
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    availableitems_id bigint REFERENCES availableitems ON DELETE SET NULL,
+    knownitems_id bigint NOT NULL REFERENCES knownitems ON DELETE CASCADE,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    amount integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS consumptions_availableitems_id_idx ON consumptions (availableitems_id);
+
+CREATE INDEX IF NOT EXISTS consumptions_household_id_created_at_idx ON consumptions (household_id, created_at);
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS consumptions (
+    id bigserial PRIMARY KEY,
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    availableitems_id bigint REFERENCES availableitems ON DELETE SET NULL,
+    knownitems_id bigint NOT NULL REFERENCES knownitems ON DELETE CASCADE,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    amount integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS consumptions_availableitems_id_idx ON consumptions (availableitems_id);
+
+CREATE INDEX IF NOT EXISTS consumptions_household_id_created_at_idx ON consumptions (household_id, created_at);
+
+INSERT INTO consumptions (household_id, availableitems_id, knownitems_id, user_id, amount, created_at)
+SELECT stock_movements.household_id, availableitems.id, stock_movements.knownitems_id, users.id, -stock_movements.quantity_change, stock_movements.created_at
+FROM stock_movements
+INNER JOIN knownitems ON knownitems.id = stock_movements.knownitems_id
+LEFT JOIN availableitems ON availableitems.id = stock_movements.availableitems_id
+LEFT JOIN users ON users.id = stock_movements.user_id
+WHERE stock_movements.kind = 'consume';
+
+DROP TABLE IF EXISTS stock_movements;
\ No newline at end of file
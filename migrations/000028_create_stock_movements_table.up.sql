@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id bigserial PRIMARY KEY,
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    availableitems_id bigint NOT NULL,
+    knownitems_id bigint NOT NULL,
+    user_id bigint,
+    kind text NOT NULL,
+    quantity_change integer NOT NULL,
+    remaining integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_kind_check CHECK (kind IN ('add', 'consume', 'discard', 'move', 'adjust'));
+
+CREATE INDEX IF NOT EXISTS stock_movements_availableitems_id_idx ON stock_movements (availableitems_id);
+
+CREATE INDEX IF NOT EXISTS stock_movements_household_id_created_at_idx ON stock_movements (household_id, created_at);
+
+INSERT INTO stock_movements (household_id, availableitems_id, knownitems_id, user_id, kind, quantity_change, remaining, created_at)
+SELECT household_id, id, knownitems_id, NULL, 'add', container_size, container_size, created_at
+FROM availableitems;
+
+INSERT INTO stock_movements (household_id, availableitems_id, knownitems_id, user_id, kind, quantity_change, remaining, created_at)
+SELECT consumptions.household_id, consumptions.availableitems_id, consumptions.knownitems_id, consumptions.user_id, 'consume', -consumptions.amount, COALESCE(availableitems.remaining, 0), consumptions.created_at
+FROM consumptions
+LEFT JOIN availableitems ON availableitems.id = consumptions.availableitems_id
+WHERE consumptions.availableitems_id IS NOT NULL;
+
+DROP TABLE IF EXISTS consumptions;
\ No newline at end of file
@@ -0,0 +1,9 @@
+DELETE FROM permissions WHERE code IN ('locations:read', 'locations:write');
+
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS from_location_id;
+
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS location_id;
+
+ALTER TABLE availableitems DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS locations;
\ No newline at end of file
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS locations (
+    id bigserial PRIMARY KEY,
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    parent_id bigint REFERENCES locations ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE locations ADD CONSTRAINT locations_parent_id_check CHECK (parent_id <> id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS locations_household_id_parent_id_name_idx ON locations (household_id, COALESCE(parent_id, 0), name);
+
+CREATE INDEX IF NOT EXISTS locations_parent_id_idx ON locations (parent_id);
+
+ALTER TABLE availableitems ADD COLUMN IF NOT EXISTS location_id bigint REFERENCES locations ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS availableitems_location_id_idx ON availableitems (location_id);
+
+/*
+    The ledger outlives locations just like it outlives items, so the
+    location columns are not foreign keys.
+*/
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS location_id bigint;
+
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS from_location_id bigint;
+
+INSERT INTO permissions (code)
+VALUES
+    ('locations:read'),
+    ('locations:write');
+
+/*
+    Anyone who could manage available items gets to manage the locations they
+    are kept in as well.
+*/
+INSERT INTO users_permissions
+SELECT users_permissions.user_id, (SELECT id FROM permissions WHERE code = 'locations:read')
+FROM users_permissions
+INNER JOIN permissions ON users_permissions.permission_id = permissions.id
+WHERE permissions.code = 'availableitems:read';
+
+INSERT INTO users_permissions
+SELECT users_permissions.user_id, (SELECT id FROM permissions WHERE code = 'locations:write')
+FROM users_permissions
+INNER JOIN permissions ON users_permissions.permission_id = permissions.id
+WHERE permissions.code = 'availableitems:write';
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE availableitems DROP COLUMN IF EXISTS frozen_at;
+
+ALTER TABLE availableitems DROP COLUMN IF EXISTS opened_at;
+
+ALTER TABLE knownitems DROP CONSTRAINT IF EXISTS knownitems_shelf_life_days_check;
+
+ALTER TABLE knownitems DROP COLUMN IF EXISTS frozen_shelf_life_days;
+
+ALTER TABLE knownitems DROP COLUMN IF EXISTS opened_shelf_life_days;
+
+ALTER TABLE knownitems DROP COLUMN IF EXISTS shelf_life_days;
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE knownitems ADD COLUMN IF NOT EXISTS shelf_life_days integer NOT NULL DEFAULT 0;
+
+ALTER TABLE knownitems ADD COLUMN IF NOT EXISTS opened_shelf_life_days integer NOT NULL DEFAULT 0;
+
+ALTER TABLE knownitems ADD COLUMN IF NOT EXISTS frozen_shelf_life_days integer NOT NULL DEFAULT 0;
+
+ALTER TABLE knownitems ADD CONSTRAINT knownitems_shelf_life_days_check CHECK (shelf_life_days >= 0 AND opened_shelf_life_days >= 0 AND frozen_shelf_life_days >= 0);
+
+ALTER TABLE availableitems ADD COLUMN IF NOT EXISTS opened_at timestamp(0) with time zone;
+
+ALTER TABLE availableitems ADD COLUMN IF NOT EXISTS frozen_at timestamp(0) with time zone;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE knownitems DROP CONSTRAINT IF EXISTS knownitems_par_level_check;
+
+ALTER TABLE knownitems DROP COLUMN IF EXISTS par_measurement;
+
+ALTER TABLE knownitems DROP COLUMN IF EXISTS par_level;
\ No newline at end of file
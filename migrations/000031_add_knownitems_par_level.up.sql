@@ -0,0 +1,5 @@
+ALTER TABLE knownitems ADD COLUMN IF NOT EXISTS par_level double precision NOT NULL DEFAULT 0;
+
+ALTER TABLE knownitems ADD COLUMN IF NOT EXISTS par_measurement bigint REFERENCES measurements(id);
+
+ALTER TABLE knownitems ADD CONSTRAINT knownitems_par_level_check CHECK (par_level >= 0);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE code IN ('shoppinglists:read', 'shoppinglists:write');
+
+DROP TABLE IF EXISTS shoppinglist_items;
+
+DROP TABLE IF EXISTS shoppinglists;
\ No newline at end of file
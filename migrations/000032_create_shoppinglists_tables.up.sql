@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS shoppinglists (
+    id bigserial PRIMARY KEY,
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS shoppinglists_household_id_idx ON shoppinglists (household_id);
+
+CREATE TABLE IF NOT EXISTS shoppinglist_items (
+    id bigserial PRIMARY KEY,
+    shoppinglist_id bigint NOT NULL REFERENCES shoppinglists ON DELETE CASCADE,
+    knownitems_id bigint REFERENCES knownitems ON DELETE CASCADE,
+    text text NOT NULL DEFAULT '',
+    quantity double precision NOT NULL DEFAULT 1,
+    measurement bigint REFERENCES measurements(id),
+    checked boolean NOT NULL DEFAULT false,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE shoppinglist_items ADD CONSTRAINT shoppinglist_items_entry_check CHECK (knownitems_id IS NOT NULL OR text <> '');
+
+ALTER TABLE shoppinglist_items ADD CONSTRAINT shoppinglist_items_quantity_check CHECK (quantity > 0);
+
+CREATE INDEX IF NOT EXISTS shoppinglist_items_shoppinglist_id_idx ON shoppinglist_items (shoppinglist_id);
+
+INSERT INTO permissions (code)
+VALUES
+    ('shoppinglists:read'),
+    ('shoppinglists:write');
+
+/*
+    Checking out a shopping list adds available items, so anyone who could
+    manage available items gets to manage shopping lists as well.
+*/
+INSERT INTO users_permissions
+SELECT users_permissions.user_id, (SELECT id FROM permissions WHERE code = 'shoppinglists:read')
+FROM users_permissions
+INNER JOIN permissions ON users_permissions.permission_id = permissions.id
+WHERE permissions.code = 'availableitems:read';
+
+INSERT INTO users_permissions
+SELECT users_permissions.user_id, (SELECT id FROM permissions WHERE code = 'shoppinglists:write')
+FROM users_permissions
+INNER JOIN permissions ON users_permissions.permission_id = permissions.id
+WHERE permissions.code = 'availableitems:write';
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ingredients_knownitems;
\ No newline at end of file
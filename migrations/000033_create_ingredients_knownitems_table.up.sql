@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ingredients_knownitems (
+    ingredient_id bigint NOT NULL REFERENCES ingredients ON DELETE CASCADE,
+    knownitems_id bigint NOT NULL REFERENCES knownitems ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ingredient_id, knownitems_id)
+);
+
+CREATE INDEX IF NOT EXISTS ingredients_knownitems_knownitems_id_idx ON ingredients_knownitems (knownitems_id);
\ No newline at end of file
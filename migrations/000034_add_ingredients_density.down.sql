@@ -0,0 +1,3 @@
+ALTER TABLE ingredients DROP CONSTRAINT IF EXISTS ingredients_density_check;
+
+ALTER TABLE ingredients DROP COLUMN IF EXISTS density;
\ No newline at end of file
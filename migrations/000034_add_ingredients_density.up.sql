@@ -0,0 +1,3 @@
+ALTER TABLE ingredients ADD COLUMN IF NOT EXISTS density double precision NOT NULL DEFAULT 0;
+
+ALTER TABLE ingredients ADD CONSTRAINT ingredients_density_check CHECK (density >= 0);
\ No newline at end of file